@@ -0,0 +1,127 @@
+package magol
+
+import (
+	"math/bits"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCacheLimit is the default number of bytes a CachingAllocator will hold on to.
+const DefaultCacheLimit = 256 << 20
+
+// minSizeClass is the smallest size class (as a power of two) handed out by the CachingAllocator.
+const minSizeClass = 6
+
+// Allocator is anything that can allocate and free Buffers. All Backends are Allocators.
+type Allocator interface {
+	Alloc(size int64) (Buffer, error)
+	Free(buf Buffer) error
+}
+
+// AllocatorStats are the bookkeeping counters of a CachingAllocator.
+type AllocatorStats struct {
+	Hits   uint64 // number of allocations served from the cache
+	Misses uint64 // number of allocations that went to the underlying Allocator
+	Cached int64  // number of bytes currently held in the cache
+}
+
+// CachingAllocator is an Allocator that rounds allocations up to power-of-two size classes
+// and keeps freed Buffers around, so that subsequent allocations of the same size class are cheap.
+//
+// At most limit bytes are cached. Buffers freed beyond that are returned to the underlying Allocator.
+type CachingAllocator struct {
+	sync.Mutex
+	a     Allocator
+	limit int64
+
+	free  map[int][]Buffer // size class → cached buffers
+	stats AllocatorStats
+}
+
+// NewCachingAllocator creates a CachingAllocator that caches at most limit bytes on top of a.
+func NewCachingAllocator(a Allocator, limit int64) *CachingAllocator {
+	return &CachingAllocator{
+		a:     a,
+		limit: limit,
+		free:  make(map[int][]Buffer),
+	}
+}
+
+// Alloc returns a Buffer of the given size. The Buffer may be a previously freed Buffer of the same size class.
+func (c *CachingAllocator) Alloc(size int64) (Buffer, error) {
+	if size < 0 {
+		return Buffer{}, errors.Errorf("Cannot allocate %d bytes", size)
+	}
+	class := sizeClass(size)
+
+	c.Lock()
+	if bufs := c.free[class]; len(bufs) > 0 {
+		buf := bufs[len(bufs)-1]
+		c.free[class] = bufs[:len(bufs)-1]
+		c.stats.Hits++
+		c.stats.Cached -= 1 << class
+		c.Unlock()
+		buf.sz = uintptr(size)
+		return buf, nil
+	}
+	c.stats.Misses++
+	c.Unlock()
+
+	buf, err := c.a.Alloc(1 << class)
+	if err != nil {
+		return Buffer{}, err
+	}
+	buf.sz = uintptr(size)
+	return buf, nil
+}
+
+// Free returns the Buffer to the cache, or to the underlying Allocator if the cache is full.
+func (c *CachingAllocator) Free(buf Buffer) error {
+	class := sizeClass(int64(buf.sz))
+	buf.sz = 1 << class
+
+	c.Lock()
+	if c.stats.Cached+(1<<class) > c.limit {
+		c.Unlock()
+		return c.a.Free(buf)
+	}
+	c.free[class] = append(c.free[class], buf)
+	c.stats.Cached += 1 << class
+	c.Unlock()
+	return nil
+}
+
+// EmptyCache returns all cached Buffers to the underlying Allocator.
+func (c *CachingAllocator) EmptyCache() error {
+	c.Lock()
+	free := c.free
+	c.free = make(map[int][]Buffer)
+	c.stats.Cached = 0
+	c.Unlock()
+
+	var err error
+	for _, bufs := range free {
+		for _, buf := range bufs {
+			if e := c.a.Free(buf); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Stats returns a snapshot of the bookkeeping counters.
+func (c *CachingAllocator) Stats() AllocatorStats {
+	c.Lock()
+	defer c.Unlock()
+	return c.stats
+}
+
+// sizeClass returns the smallest n such that size <= 1<<n.
+func sizeClass(size int64) int {
+	if size <= 1<<minSizeClass {
+		return minSizeClass
+	}
+	return bits.Len64(uint64(size - 1))
+}
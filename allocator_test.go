@@ -0,0 +1,133 @@
+package magol
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAllocator hands out fake Buffers and records what was allocated and freed.
+type fakeAllocator struct {
+	allocs []int64
+	frees  []uintptr
+}
+
+func (a *fakeAllocator) Alloc(size int64) (Buffer, error) {
+	a.allocs = append(a.allocs, size)
+	return Buffer{b: unsafe.Pointer(new(uint64)), sz: uintptr(size)}, nil
+}
+
+func (a *fakeAllocator) Free(buf Buffer) error {
+	a.frees = append(a.frees, buf.sz)
+	return nil
+}
+
+func TestSizeClass(t *testing.T) {
+	cases := []struct {
+		size  int64
+		class int
+	}{
+		{0, minSizeClass},
+		{1, minSizeClass},
+		{64, 6},
+		{65, 7},
+		{128, 7},
+		{129, 8},
+		{1 << 20, 20},
+		{1<<20 + 1, 21},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.class, sizeClass(c.size), "size %d", c.size)
+	}
+}
+
+func TestCachingAllocator(t *testing.T) {
+	assert := assert.New(t)
+	fake := new(fakeAllocator)
+	c := NewCachingAllocator(fake, 1024)
+
+	a, err := c.Alloc(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(uintptr(100), a.MemSize())
+	assert.Equal([]int64{128}, fake.allocs, "allocations are rounded up to the size class")
+	assert.Equal(AllocatorStats{Misses: 1}, c.Stats())
+
+	// freed memory is cached, and reused for allocations of the same size class
+	if err := c.Free(a); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(fake.frees)
+	assert.Equal(AllocatorStats{Misses: 1, Cached: 128}, c.Stats())
+
+	b, err := c.Alloc(120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(a.b == b.b, "expected the cached Buffer to be reused")
+	assert.Equal(uintptr(120), b.MemSize())
+	assert.Equal(AllocatorStats{Hits: 1, Misses: 1}, c.Stats())
+
+	// a different size class misses
+	d, err := c.Alloc(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]int64{128, 1024}, fake.allocs)
+	assert.Equal(AllocatorStats{Hits: 1, Misses: 2}, c.Stats())
+
+	// beyond the limit, freed memory is returned to the underlying allocator
+	if err := c.Free(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Free(d); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]uintptr{1024}, fake.frees)
+	assert.Equal(int64(128), c.Stats().Cached)
+
+	if err := c.EmptyCache(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]uintptr{1024, 128}, fake.frees)
+	assert.Equal(int64(0), c.Stats().Cached)
+}
+
+func TestEngine_CachingAllocator(t *testing.T) {
+	e := NewEngineWithBackend(NewHostBackend())
+	for i := 0; i < 10; i++ {
+		mem, err := e.Alloc(24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Free(mem, 24); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, AllocatorStats{Hits: 9, Misses: 1, Cached: 64}, e.AllocatorStats())
+	if err := e.EmptyCache(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), e.AllocatorStats().Cached)
+
+	// a Buffer that can't be registered goes back to the allocator
+	mem, err := e.Alloc(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := e.reg.remove(mem.Uintptr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.alloc.Free(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.reg.insert(buf); err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.Alloc(24)
+	assert.Error(t, err, "the cached Buffer is still registered")
+	assert.Equal(t, int64(64), e.AllocatorStats().Cached, "the Buffer should be cached again")
+}
@@ -0,0 +1,256 @@
+package magol
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+func (e *Engine) Add(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Add()", "add", a, b, opts...)
+}
+
+func (e *Engine) Sub(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Sub()", "sub", a, b, opts...)
+}
+
+func (e *Engine) Mul(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Mul()", "mul", a, b, opts...)
+}
+
+func (e *Engine) Div(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Div()", "div", a, b, opts...)
+}
+
+func (e *Engine) Pow(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Pow()", "pow", a, b, opts...)
+}
+
+func (e *Engine) Mod(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("Mod()", "mod", a, b, opts...)
+}
+
+func (e *Engine) MinBetween(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("MinBetween()", "min", a, b, opts...)
+}
+
+func (e *Engine) MaxBetween(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.binOp("MaxBetween()", "max", a, b, opts...)
+}
+
+func (e *Engine) AddScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("AddScalar()", "add", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) SubScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("SubScalar()", "sub", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) MulScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("MulScalar()", "mul", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) DivScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("DivScalar()", "div", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) PowScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("PowScalar()", "pow", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) ModScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("ModScalar()", "mod", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) MinBetweenScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("MinBetweenScalar()", "min", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) MaxBetweenScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.scalarOp("MaxBetweenScalar()", "max", a, b, leftTensor, opts...)
+}
+
+// binOp runs the kernel of op elementwise over a and b, broadcasting them against each other.
+// fn is the name of the calling method, for errors.
+func (e *Engine) binOp(fn, op string, a, b tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdBinOps[op](std, a, b, opts...)
+		return err
+	}, a, b); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.binOp(fn, op, a, b) })
+	}
+	if err = e.checkValidDtype(a, b); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	shape, err := broadcastShape(a.Shape(), b.Shape())
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	kernel := func(strided bool) (string, error) { return stridedName(op, strided, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if retVal, err = e.prepResult(a, shape, opts...); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.runElementwise(kernel, shape, retVal, a, b); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
+
+// scalarOp runs the scalar kernel of op elementwise over a, with b as the other operand.
+// leftTensor indicates if a is the left operand. fn is the name of the calling method, for errors.
+func (e *Engine) scalarOp(fn, op string, a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdScalarOps[op](std, a, b, leftTensor, opts...)
+		return err
+	}, a); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.scalarOp(fn, op, a, b, leftTensor) })
+	}
+	scalar, err := e.checkValidScalar(a, b)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	kernel := func(strided bool) (string, error) { return scalarKernelName(op, leftTensor, strided, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if retVal, err = e.prepResult(a, a.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.runScalar(kernel, a, retVal, scalar); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
+
+// checkElementwise checks that the library has both the flat and the strided kernels named by kernel, for the Dtype dt.
+func (e *Engine) checkElementwise(kernel func(strided bool) (string, error), dt tensor.Dtype) error {
+	for _, strided := range []bool{false, true} {
+		name, err := kernel(strided)
+		if err != nil {
+			return err
+		}
+		if err = e.checkKernel(dt, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runElementwise runs the kernel named by kernel, flat or strided, elementwise over the operands broadcast to shape, into retVal.
+func (e *Engine) runElementwise(kernel func(strided bool) (string, error), shape tensor.Shape, retVal tensor.Tensor, operands ...tensor.Tensor) error {
+	// the flat kernel is used when the operands and retVal can all be indexed flatly
+	strided := !isRowMajor(retVal)
+	for _, t := range operands {
+		strided = strided || !t.Shape().Eq(shape) || !isRowMajor(t)
+	}
+	name, err := kernel(strided)
+	if err != nil {
+		return err
+	}
+	var consts [][]byte
+	if strided {
+		strides := make([][]int, len(operands))
+		for i, t := range operands {
+			strides[i] = broadcastStrides(shape, t.Shape(), t.Strides())
+		}
+		l, err := newStridedLayout(shape, retVal.Strides(), strides...)
+		if err != nil {
+			return err
+		}
+		consts = [][]byte{bytesOf(l)}
+	}
+	mems := make([]tensor.Memory, 0, len(operands)+1)
+	for _, t := range operands {
+		mems = append(mems, t)
+	}
+	return e.dispatch(name, shape.TotalSize(), consts, append(mems, retVal)...)
+}
+
+// runScalar runs the kernel named by kernel, flat or strided, elementwise over a, with the bytes of the scalars if it takes any, into retVal.
+// Unary ops are run by it with no scalars.
+func (e *Engine) runScalar(kernel func(strided bool) (string, error), a, retVal tensor.Tensor, scalars ...[]byte) error {
+	strided := !isRowMajor(a) || !isRowMajor(retVal)
+	name, err := kernel(strided)
+	if err != nil {
+		return err
+	}
+	consts := append([][]byte(nil), scalars...)
+	if strided {
+		l, err := newStridedLayout(a.Shape(), retVal.Strides(), a.Strides())
+		if err != nil {
+			return err
+		}
+		consts = append(consts, bytesOf(l))
+	}
+	return e.dispatch(name, a.Shape().TotalSize(), consts, a, retVal)
+}
+
+// checkValidScalar checks that a is of a Dtype kernels are generated for, and b a scalar of it, returning the bytes of b.
+func (e *Engine) checkValidScalar(a tensor.Tensor, b interface{}) ([]byte, error) {
+	if err := e.checkValidDtype(a); err != nil {
+		return nil, err
+	}
+	if t := reflect.TypeOf(b); t != a.Dtype().Type {
+		return nil, errors.Errorf("Expected a scalar of %v. Got %v instead", a.Dtype(), t)
+	}
+	return bytesOfScalar(b)
+}
+
+// incrOf returns the tensor the result of an op is added to, if opts has a tensor.WithIncr.
+func incrOf(opts []tensor.FuncOpt) (tensor.Tensor, bool) {
+	incr, ok := tensor.ParseFuncOpts(opts...).IncrReuse()
+	return incr, ok && incr != nil
+}
+
+// accumulate adds the result of op to incr, as tensor.WithIncr asks for.
+func (e *Engine) accumulate(incr tensor.Tensor, op func() (tensor.Tensor, error)) (tensor.Tensor, error) {
+	tmp, err := op()
+	if err != nil {
+		return nil, err
+	}
+	defer e.release(tmp)
+	return e.Add(incr, tmp, tensor.UseUnsafe())
+}
+
+// prepResult returns the tensor that the result of an elementwise op over a, of the given shape, goes into,
+// as dictated by opts: a itself when unsafe, the reuse tensor if one is given, or a newly allocated tensor.
+// Ops with a tensor.WithIncr option are run without it, and their result accumulated into the incr tensor, before prepResult.
+func (e *Engine) prepResult(a tensor.Tensor, shape tensor.Shape, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.prepResultOf(a, shape, a.Dtype(), opts...)
+}
+
+// releaseResult releases retVal, the result of a failed op over a, if prepResult allocated it, rather than handing out a or the reuse tensor.
+func (e *Engine) releaseResult(retVal, a tensor.Tensor, opts []tensor.FuncOpt) {
+	if retVal != a && retVal != tensor.ParseFuncOpts(opts...).Reuse() {
+		e.release(retVal)
+	}
+}
+
+// prepResultOf is prepResult for results of the Dtype dt, which must be that of a for unsafe ops.
+func (e *Engine) prepResultOf(a tensor.Tensor, shape tensor.Shape, dt tensor.Dtype, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	reuse, safe, _, _, err := e.handleFuncOpts(shape, dt, a.DataOrder(), opts...)
+	switch {
+	case err != nil:
+		return nil, err
+	case !safe:
+		if !a.Shape().Eq(shape) {
+			return nil, errors.Errorf("Cannot write a result of shape %v into a tensor of shape %v in place", shape, a.Shape())
+		}
+		return a, nil
+	case reuse != nil:
+		return reuse, nil
+	}
+	return e.makeTensor(shape, dt)
+}
@@ -0,0 +1,175 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_BinOps(t *testing.T) {
+	backingA := []float32{1.5, 2, 3, 4.25, 5, 6}
+	backingB := []float32{2, 0.5, 3, 1.5, 4, 7}
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std  func(a, b interface{}, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"Add", (*Engine).Add, tensor.Add},
+		{"Sub", (*Engine).Sub, tensor.Sub},
+		{"Mul", (*Engine).Mul, tensor.Mul},
+		{"Div", (*Engine).Div, tensor.Div},
+		{"Pow", (*Engine).Pow, tensor.Pow},
+		{"Mod", (*Engine).Mod, tensor.Mod},
+		{"MinBetween", (*Engine).MinBetween, tensor.MinBetween},
+		{"MaxBetween", (*Engine).MaxBetween, tensor.MaxBetween},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			assert := assert.New(t)
+			e := newTestEngine(t)
+			expected, err := op.std(
+				tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backingA...))),
+				tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backingB...))),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expected.Data().([]float32)
+
+			a := engineTensor(t, e, backingA, 2, 3)
+			b := engineTensor(t, e, backingB, 2, 3)
+
+			// safe
+			c, err := op.eng(e, a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(allWithinRange(want, readback(t, e, c).Data().([]float32), closef32), "safe")
+			assert.Equal(backingA, readback(t, e, a).Data(), "a should be untouched")
+
+			// reuse
+			reuse := engineTensor(t, e, make([]float32, 6), 6)
+			c, err = op.eng(e, a, b, tensor.WithReuse(reuse))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(c == reuse)
+			assert.True(allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "reuse")
+
+			// unsafe
+			c, err = op.eng(e, a, b, tensor.UseUnsafe())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(c == a)
+			assert.True(allWithinRange(want, readback(t, e, a).Data().([]float32), closef32), "unsafe")
+		})
+	}
+}
+
+func TestEngine_BinOpErrors(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	b := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 3, 2)
+	_, err := e.Sub(a, b)
+	assert.Error(t, err, "shapes differ")
+
+	f64 := tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float64))
+	_, err = e.Mul(a, f64)
+	assert.Error(t, err, "dtypes differ")
+
+	// ops with no kernel for the Dtype fail before allocating their result
+	i := engineCopy(t, e, tensor.New(tensor.WithBacking([]int32{1, 2, 3})))
+	live := liveAllocs(e)
+	_, err = e.Pow(i, i)
+	assert.EqualError(t, err, "Pow(): Unsupported Dtype int32: there is no kernel pow_i32")
+	_, err = e.PowScalar(i, int32(2), true)
+	assert.Error(t, err, "Int32 PowScalar")
+	assert.Equal(t, live, liveAllocs(e), "nothing should have been allocated")
+
+	// results are released when the op fails after allocating them, here as b is not in the memory of the Engine
+	_, err = e.Add(a, tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)))
+	assert.Error(t, err, "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the result should have been released")
+}
+
+// liveAllocs returns the number of allocations of e that are neither freed nor released.
+func liveAllocs(e *Engine) int {
+	e.q.Lock()
+	defer e.q.Unlock()
+	e.reg.RLock()
+	defer e.reg.RUnlock()
+	return len(e.reg.bufs) - len(e.q.released)
+}
+
+func TestEngine_ScalarOps(t *testing.T) {
+	backing := []float32{1.5, 2, 3, 4.25, 5, 6}
+	var s float32 = 2.5
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std  func(a, b interface{}, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"AddScalar", (*Engine).AddScalar, tensor.Add},
+		{"SubScalar", (*Engine).SubScalar, tensor.Sub},
+		{"MulScalar", (*Engine).MulScalar, tensor.Mul},
+		{"DivScalar", (*Engine).DivScalar, tensor.Div},
+		{"PowScalar", (*Engine).PowScalar, tensor.Pow},
+		{"ModScalar", (*Engine).ModScalar, tensor.Mod},
+		{"MinBetweenScalar", (*Engine).MinBetweenScalar, tensor.MinBetween},
+		{"MaxBetweenScalar", (*Engine).MaxBetweenScalar, tensor.MaxBetween},
+	}
+	for _, op := range ops {
+		for _, leftTensor := range []bool{true, false} {
+			name := op.name + "/scalar_left"
+			if leftTensor {
+				name = op.name + "/tensor_left"
+			}
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				e := newTestEngine(t)
+				std := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backing...)))
+				var expected tensor.Tensor
+				var err error
+				if leftTensor {
+					expected, err = op.std(std, s)
+				} else {
+					expected, err = op.std(s, std)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := expected.Data().([]float32)
+
+				a := engineTensor(t, e, backing, 2, 3)
+				c, err := op.eng(e, a, s, leftTensor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.True(allWithinRange(want, readback(t, e, c).Data().([]float32), closef32), "safe")
+				assert.Equal(backing, readback(t, e, a).Data(), "a should be untouched")
+
+				reuse := engineTensor(t, e, make([]float32, 6), 2, 3)
+				if c, err = op.eng(e, a, s, leftTensor, tensor.WithReuse(reuse)); err != nil {
+					t.Fatal(err)
+				}
+				assert.True(c == reuse)
+				assert.True(allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "reuse")
+
+				if c, err = op.eng(e, a, s, leftTensor, tensor.UseUnsafe()); err != nil {
+					t.Fatal(err)
+				}
+				assert.True(c == a)
+				assert.True(allWithinRange(want, readback(t, e, a).Data().([]float32), closef32), "unsafe")
+			})
+		}
+	}
+}
+
+func TestEngine_ScalarOpErrors(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	_, err := e.AddScalar(a, 2.0, true)
+	assert.Error(t, err, "float64 scalar for a Float32 tensor")
+}
@@ -0,0 +1,160 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Backend is the set of device primitives that an Engine is built on.
+//
+// The Metal backend (darwin only) implements it with MTLBuffers and Metal Performance Shaders.
+// The HostBackend implements it on plain Go memory, so that the Engine may be used and tested on any platform.
+//
+// Ops (Dispatch, DispatchGroups, MatMul, MatVecMul and SoftMax) are only encoded, in order, into a pending batch of work.
+// They are not guaranteed to have run until the Completion returned by Commit is done.
+// Committed batches run in the order they were committed.
+// The copying methods act on memory immediately, so all committed work must be done before they are called.
+type Backend interface {
+	// Alloc allocates size bytes of memory on the device.
+	Alloc(size int64) (Buffer, error)
+	// Free releases memory previously allocated by Alloc.
+	Free(buf Buffer) error
+
+	// CopyIn copies host memory into the device buffer.
+	CopyIn(dst Buffer, src []byte) error
+	// CopyOut copies the device buffer into host memory.
+	CopyOut(dst []byte, src Buffer) error
+	// Copy copies between two device buffers.
+	Copy(dst, src Buffer) error
+	// Fill sets the device buffer to repeated copies of pattern.
+	Fill(dst Buffer, pattern []byte) error
+
+	// Dispatch runs the named elementwise kernel over n elements.
+	// args are bound to the kernel's buffer arguments in order, followed by each of consts as a constant argument.
+	// consts are copied at encode time.
+	Dispatch(kernel string, n int, consts [][]byte, args ...Buffer) error
+	// DispatchGroups runs the named reduction kernel over groups threadgroups of reduceThreads threads each.
+	// Its arguments are bound like Dispatch's.
+	DispatchGroups(kernel string, groups int, consts [][]byte, args ...Buffer) error
+
+	// MatMul performs C = alpha·op(A)·op(B) + beta·C, where op transposes its input if the corresponding flag is set.
+	// C is not read if beta is 0.
+	// If the layouts describe batches of matrices, which they must all have the same number of, each matrix of C is the product of the corresponding matrices of A and B.
+	MatMul(a, b, c Buffer, al, bl, cl MatrixLayout, transA, transB bool, alpha, beta float32) error
+	// MatVecMul performs y = op(A) × x.
+	MatVecMul(a, x, y Buffer, al MatrixLayout, xl, yl VectorLayout, transA bool) error
+	// SoftMax performs a row-wise softmax of x, storing the result in out.
+	SoftMax(x, out Buffer, xl, outl MatrixLayout) error
+
+	// Commit commits the pending batch of work for execution without waiting for it.
+	Commit() *Completion
+	// Discard drops the pending batch of work without executing it.
+	Discard()
+}
+
+// MatrixLayout describes how a row major matrix, or a batch of them, is laid out in a Buffer.
+type MatrixLayout struct {
+	Rows, Cols int
+	RowBytes   int
+	Dtype      tensor.Dtype
+
+	Matrices    int // the number of matrices in the batch. 0 is the same as 1
+	MatrixBytes int // the stride between consecutive matrices of the batch
+}
+
+// batches returns the number of matrices l describes.
+func (l MatrixLayout) batches() int {
+	if l.Matrices < 1 {
+		return 1
+	}
+	return l.Matrices
+}
+
+// linalgDtypes are the Dtypes the matrix ops of Backends, MatMul, MatVecMul and SoftMax, support.
+// MPS has no bfloat16 matrices.
+var linalgDtypes = []tensor.Dtype{tensor.Float32, Float16}
+
+func isLinalgDtype(dt tensor.Dtype) bool {
+	for _, l := range linalgDtypes {
+		if l == dt {
+			return true
+		}
+	}
+	return false
+}
+
+// VectorLayout describes how a vector is laid out in a Buffer.
+type VectorLayout struct {
+	Length int
+	Dtype  tensor.Dtype
+}
+
+// matrixLayout returns the layout of the row major matrix backing the 2D tensor t.
+// trans reports whether t is the transpose of that matrix, as is the case for transposed views and column major tensors.
+// Tensors that are strided along both axes can not be described by a MatrixLayout, and have to be materialized first.
+func matrixLayout(t tensor.DenseTensor) (l MatrixLayout, trans bool, err error) {
+	shp, strides := t.Shape(), t.Strides()
+	if len(shp) != 2 || len(strides) != 2 {
+		return MatrixLayout{}, false, errors.New("Expected Matrix")
+	}
+	return matrixLayoutOf(shp, strides, t.Dtype())
+}
+
+// batchLayout returns the layout of the batch of row major matrices backing the 3D tensor t, whose first axis is the batch.
+// Like in matrixLayout, trans reports whether the matrices of t are transposed.
+// The matrices must not overlap, so that they may be written to.
+func batchLayout(t tensor.DenseTensor) (l MatrixLayout, trans bool, err error) {
+	shp, strides := t.Shape(), t.Strides()
+	if len(shp) != 3 || len(strides) != 3 {
+		return MatrixLayout{}, false, errors.New("Expected a batch of matrices")
+	}
+	if l, trans, err = matrixLayoutOf(shp[1:], strides[1:], t.Dtype()); err != nil {
+		return MatrixLayout{}, false, err
+	}
+	l.Matrices = shp[0]
+	l.MatrixBytes = strides[0] * int(t.Dtype().Size())
+	if l.Matrices == 1 {
+		// single matrices may have any stride
+		l.MatrixBytes = l.Rows * l.RowBytes
+	}
+	if l.RowBytes > 0 && (l.MatrixBytes < l.Rows*l.RowBytes || l.MatrixBytes%l.RowBytes != 0) {
+		return MatrixLayout{}, false, errors.Errorf("Cannot describe a batch of matrices with strides %v", strides)
+	}
+	return l, trans, nil
+}
+
+// matrixLayoutOf returns the layout of the row major matrix of the given shape and strides.
+func matrixLayoutOf(shp tensor.Shape, strides []int, dt tensor.Dtype) (l MatrixLayout, trans bool, err error) {
+	size := int(dt.Size())
+	switch {
+	case strides[1] == 1 || shp[1] == 1:
+		l = MatrixLayout{Rows: shp[0], Cols: shp[1], RowBytes: strides[0] * size, Dtype: dt}
+	case strides[0] == 1 || shp[0] == 1:
+		l = MatrixLayout{Rows: shp[1], Cols: shp[0], RowBytes: strides[1] * size, Dtype: dt}
+		trans = true
+	default:
+		return MatrixLayout{}, false, errors.Errorf("Cannot describe a matrix with strides %v", strides)
+	}
+	if l.RowBytes < l.Cols*size {
+		// single rows may have any stride
+		l.RowBytes = l.Cols * size
+	}
+	return l, trans, nil
+}
+
+func vectorLayout(t tensor.DenseTensor) (VectorLayout, error) {
+	// the strides of t don't matter, as vectors are only ever passed to the Backend contiguously
+	if !t.Shape().IsVectorLike() {
+		return VectorLayout{}, errors.New("Expected a vectorlike matrix")
+	}
+	return VectorLayout{Length: getVecLen(t.Shape()), Dtype: t.Dtype()}, nil
+}
+
+func getVecLen(s tensor.Shape) int {
+	for _, d := range s {
+		if d != 1 {
+			return d
+		}
+	}
+	return 1
+}
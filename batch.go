@@ -0,0 +1,175 @@
+package magol
+
+import (
+	"context"
+	"sync"
+
+	"gorgonia.org/tensor"
+)
+
+// ErrCanceled is returned when work is abandoned because the context of the Engine is done.
+// Err is the error of the context: context.Canceled or context.DeadlineExceeded.
+type ErrCanceled struct{ Err error }
+
+func (e ErrCanceled) Error() string { return "Work abandoned: " + e.Err.Error() }
+func (e ErrCanceled) Unwrap() error { return e.Err }
+
+// batcher tracks the work encoded on a Backend by an Engine, and all the Engines derived from it by WithContext.
+//
+// The pending batch only ever holds the ops of one Engine: when another Engine encodes an op, the pending batch
+// is committed first. This way the uncommitted work of a canceled Engine can be dropped without affecting others.
+type batcher struct {
+	sync.Mutex
+	owner    *Engine         // the Engine whose ops are in the pending batch
+	pending  int             // number of ops encoded since the last commit
+	inflight []*Completion   // committed batches that have not been waited on
+	last     *Completion     // the last committed batch that had ops in it
+	released []tensor.Tensor // temporaries to free once the work encoded so far is done
+}
+
+// WithContext returns a copy of the Engine whose ops are bound to ctx.
+//
+// Once ctx is done, ops of the returned Engine that have been encoded but not committed are dropped,
+// and its ops, Sync, and anything that waits on the device return ErrCanceled.
+// Work that has already been committed can not be recalled. It is left to complete.
+func (e *Engine) WithContext(ctx context.Context) *Engine {
+	retVal := *e
+	retVal.ctx = ctx
+	return &retVal
+}
+
+// Context returns the context the Engine's ops are bound to.
+func (e *Engine) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// Commit commits all pending ops for execution, without waiting for them to complete.
+// The host may prepare further work while the returned Completion is not done.
+func (e *Engine) Commit() *Completion {
+	if err := e.checkCtx(); err != nil {
+		c := newCompletion()
+		c.complete(err)
+		return c
+	}
+	e.q.Lock()
+	defer e.q.Unlock()
+	return e.commit()
+}
+
+// Sync commits all pending ops and waits for all committed work to complete.
+// The first error any of the work failed with is returned.
+func (e *Engine) Sync() error {
+	if err := e.checkCtx(); err != nil {
+		return err
+	}
+	e.q.Lock()
+	e.commit()
+	inflight := e.q.inflight
+	e.q.inflight = nil
+	e.q.Unlock()
+
+	var err error
+	for i, c := range inflight {
+		select {
+		case <-c.Done():
+			if cerr := c.Err(); cerr != nil && err == nil {
+				err = cerr
+			}
+		case <-e.Context().Done():
+			// leave the rest to be waited on by a later Sync
+			e.q.Lock()
+			e.q.inflight = append(inflight[i:], e.q.inflight...)
+			e.q.Unlock()
+			return ErrCanceled{e.ctx.Err()}
+		}
+	}
+	return err
+}
+
+// encode encodes an op into the pending batch, committing the batch if it is full.
+func (e *Engine) encode(op func() error) error {
+	if err := e.checkCtx(); err != nil {
+		return err
+	}
+	e.q.Lock()
+	defer e.q.Unlock()
+	if e.q.owner != e && e.q.pending > 0 {
+		e.commit()
+	}
+	if err := op(); err != nil {
+		return err
+	}
+	e.q.owner = e
+	e.q.pending++
+	if e.q.pending >= e.batchSize {
+		e.commit()
+	}
+	return nil
+}
+
+// commit commits the pending batch. The caller must hold the lock of the batcher.
+// Released temporaries are freed once the batch, and with it all the work encoded before them, is done.
+//
+// Only batches with work in them are kept for Sync to wait on, and those that have already completed without an error are dropped,
+// so that the inflight batches don't pile up in programs that never Sync.
+func (e *Engine) commit() *Completion {
+	c := e.b.Commit()
+	if e.q.pending > 0 {
+		e.q.last = c
+		e.q.inflight = append(pruneCompleted(e.q.inflight), c)
+	}
+	e.q.owner = nil
+	e.q.pending = 0
+
+	if released, last := e.q.released, e.q.last; len(released) > 0 {
+		e.q.released = nil
+		go func() {
+			if last != nil {
+				<-last.Done()
+			}
+			for _, t := range released {
+				e.Free(t, int64(t.MemSize()))
+			}
+		}()
+	}
+	return c
+}
+
+// pruneCompleted drops the completions of cs that are done without an error, in place.
+func pruneCompleted(cs []*Completion) []*Completion {
+	kept := cs[:0]
+	for _, c := range cs {
+		select {
+		case <-c.Done():
+			if c.Err() == nil {
+				continue
+			}
+		default:
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// checkCtx returns ErrCanceled if the context of the Engine is done, dropping the Engine's uncommitted work.
+func (e *Engine) checkCtx() error {
+	if e.ctx == nil {
+		return nil
+	}
+	select {
+	case <-e.ctx.Done():
+	default:
+		return nil
+	}
+	e.q.Lock()
+	if e.q.owner == e {
+		e.b.Discard()
+		e.q.owner = nil
+		e.q.pending = 0
+	}
+	e.q.Unlock()
+	return ErrCanceled{e.ctx.Err()}
+}
@@ -0,0 +1,101 @@
+package magol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_WithContext(t *testing.T) {
+	assert := assert.New(t)
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb)
+
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := engineTensor(t, e, []float32{10, 20, 30}, 3)
+	c := engineTensor(t, e, []float32{1, 1, 1}, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ce := e.WithContext(ctx)
+	if _, err := ce.Add(a, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 1)
+
+	// work of another Engine commits the pending batch of ce
+	if _, err := e.Add(c, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ce.Add(a, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 1)
+
+	// canceling drops the uncommitted work
+	cancel()
+	_, err := ce.Add(a, b, tensor.UseUnsafe())
+	var canceled ErrCanceled
+	if !errors.As(err, &canceled) {
+		t.Fatalf("Expected ErrCanceled. Got %v", err)
+	}
+	assert.True(errors.Is(err, context.Canceled))
+	assert.Len(hb.queue, 0)
+	assert.Error(ce.Sync())
+	assert.Error(ce.Commit().Wait())
+
+	if err = e.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]float32{11, 22, 33}, readback(t, e, a).Data(), "only committed work should have run")
+	assert.Equal([]float32{11, 21, 31}, readback(t, e, c).Data())
+}
+
+func TestEngine_WithContextDeadline(t *testing.T) {
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb)
+
+	block := make(chan struct{})
+	defer close(block)
+	hb.enqueue(func() { <-block })
+	e.q.pending++
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := e.WithContext(ctx).Sync()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded. Got %v", err)
+	}
+	var canceled ErrCanceled
+	assert.True(t, errors.As(err, &canceled))
+	assert.Len(t, e.q.inflight, 1, "the committed batch should still be waited on by a later Sync")
+}
+
+func TestEngine_ReleaseDeferred(t *testing.T) {
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb)
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+
+	block := make(chan struct{})
+	hb.enqueue(func() { <-block })
+	e.q.pending++
+
+	tmp, err := e.Exp(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.release(tmp)
+	mem, err := e.Alloc(int64(tmp.MemSize()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, tmp.Uintptr(), mem.Uintptr(), "the memory of a temporary should not be reused while ops may use it")
+
+	close(block)
+	if err = e.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
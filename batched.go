@@ -0,0 +1,148 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// BatchedMatMul multiplies each matrix of the batch a, of shape (B, M, K), by the corresponding matrix of the batch b, of shape (B, K, N),
+// into prealloc, of shape (B, M, N). b may also be a single (K, N) matrix, which every matrix of a is multiplied by.
+//
+// Like MatMul, the matrices may be transposed views.
+func (e *Engine) BatchedMatMul(a, b, prealloc tensor.Tensor) error {
+	ad, bd, retVal, err := e.checkValidBatchedMatMulInput(a, b, prealloc)
+	if err != nil {
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	if bd.Dims() == 2 {
+		if err = e.sharedMatMul(ad, bd, retVal); err != nil {
+			return errors.Wrap(err, "BatchedMatMul()")
+		}
+		return nil
+	}
+
+	ad, al, transA, err := e.batchOperand(ad, true)
+	if err != nil {
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	if ad != a {
+		defer e.release(ad)
+	}
+	bd, bl, transB, err := e.batchOperand(bd, true)
+	if err != nil {
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	if bd != b {
+		defer e.release(bd)
+	}
+	cd, cl, transC, err := e.batchResult(retVal, true)
+	if err != nil {
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+
+	bufs, err := e.buffersOf(ad, bd, cd)
+	if err != nil {
+		e.discardResult(retVal, cd)
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	if err = e.encodeMatMul(bufs, al, bl, cl, transA, transB, transC, 1, 0); err != nil {
+		e.discardResult(retVal, cd)
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	if err = e.finishResult(retVal, cd); err != nil {
+		return errors.Wrap(err, "BatchedMatMul()")
+	}
+	return nil
+}
+
+// sharedMatMul multiplies each matrix of the batch a by the matrix b.
+//
+// If a and the result are row major, the batch is multiplied as one (B×M, K) matrix.
+// Otherwise, as MPS can only multiply batches of the same number of matrices, each matrix is multiplied on its own.
+func (e *Engine) sharedMatMul(a, b, retVal tensor.DenseTensor) error {
+	bd, bl, transB, err := e.matrixOperand(b, true)
+	if err != nil {
+		return err
+	}
+	if bd != b {
+		defer e.release(bd)
+	}
+
+	if isRowMajor(a) && isRowMajor(retVal) {
+		shp, size := a.Shape(), int(a.Dtype().Size())
+		n := retVal.Shape()[2]
+		al := MatrixLayout{Rows: shp[0] * shp[1], Cols: shp[2], RowBytes: shp[2] * size, Dtype: a.Dtype()}
+		cl := MatrixLayout{Rows: shp[0] * shp[1], Cols: n, RowBytes: n * size, Dtype: retVal.Dtype()}
+		bufs, err := e.buffersOf(a, bd, retVal)
+		if err != nil {
+			return err
+		}
+		return e.encodeMatMul(bufs, al, bl, cl, false, transB, false, 1, 0)
+	}
+
+	ad, al, transA, err := e.batchOperand(a, true)
+	if err != nil {
+		return err
+	}
+	if ad != a {
+		defer e.release(ad)
+	}
+	cd, cl, transC, err := e.batchResult(retVal, true)
+	if err != nil {
+		return err
+	}
+	bufs, err := e.buffersOf(ad, bd, cd)
+	if err != nil {
+		e.discardResult(retVal, cd)
+		return err
+	}
+	for i := 0; i < cl.batches(); i++ {
+		aOff, cOff := uintptr(i*al.MatrixBytes), uintptr(i*cl.MatrixBytes)
+		matrix := []Buffer{bufs[0].view(aOff, bufs[0].sz-aOff), bufs[1], bufs[2].view(cOff, bufs[2].sz-cOff)}
+		if err = e.encodeMatMul(matrix, single(al), bl, single(cl), transA, transB, transC, 1, 0); err != nil {
+			e.discardResult(retVal, cd)
+			return err
+		}
+	}
+	return e.finishResult(retVal, cd)
+}
+
+// single returns the layout of one matrix of the batch described by l.
+func single(l MatrixLayout) MatrixLayout {
+	l.Matrices, l.MatrixBytes = 0, 0
+	return l
+}
+
+func (e *Engine) checkValidBatchedMatMulInput(a, b, ret tensor.Tensor) (ad, bd, retVal tensor.DenseTensor, err error) {
+	if err = e.checkLinalgDtype(a, b, ret); err != nil {
+		return nil, nil, nil, err
+	}
+	ad, ok := a.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected a to be a DenseTensor")
+	}
+	bd, ok = b.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected b to be a DenseTensor")
+	}
+	retVal, ok = ret.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected retVal to be a DenseTensor")
+	}
+
+	as, bs, rs := ad.Shape(), bd.Shape(), retVal.Shape()
+	if as.Dims() != 3 || rs.Dims() != 3 || (bs.Dims() != 3 && bs.Dims() != 2) {
+		return nil, nil, nil, errors.Errorf("Expected a and retVal to be 3D, and b to be 3D or 2D. Got %v, %v and %v instead", as, bs, rs)
+	}
+	k, n := bs[bs.Dims()-2], bs[bs.Dims()-1]
+	if bs.Dims() == 3 && bs[0] != as[0] {
+		return nil, nil, nil, errors.Errorf("Expected a and b to have the same number of matrices. Got %v and %v instead", as, bs)
+	}
+	if as[2] != k {
+		return nil, nil, nil, errors.Errorf("Expected the inner dimensions of a and b to match. Got %v and %v instead", as, bs)
+	}
+	if !rs.Eq(tensor.Shape{as[0], as[1], n}) {
+		return nil, nil, nil, errors.Errorf("Expected retVal to be of shape %v. Got %v instead", tensor.Shape{as[0], as[1], n}, rs)
+	}
+	return ad, bd, retVal, nil
+}
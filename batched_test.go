@@ -0,0 +1,139 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+// naiveBatchedMatMul multiplies the row major batches a and b. b is shared by all the matrices of a if shared is set.
+func naiveBatchedMatMul(a, b []float32, batches, m, k, n int, shared bool) []float32 {
+	retVal := make([]float32, batches*m*n)
+	for p := 0; p < batches; p++ {
+		bOff := p * k * n
+		if shared {
+			bOff = 0
+		}
+		for i := 0; i < m; i++ {
+			for j := 0; j < n; j++ {
+				var sum float32
+				for l := 0; l < k; l++ {
+					sum += a[p*m*k+i*k+l] * b[bOff+l*n+j]
+				}
+				retVal[p*m*n+i*n+j] = sum
+			}
+		}
+	}
+	return retVal
+}
+
+// batchOf returns an engine tensor of the given shape with the row major data, laid out as described by layout:
+// "row" for row major, "trans" for batches of transposed matrices, and "batch" for the batch axis being the middle one.
+func batchOf(t *testing.T, e *Engine, data []float32, layout string, shape ...int) *tensor.Dense {
+	switch layout {
+	case "trans":
+		std := stdTensor(data, shape...)
+		if err := std.T(0, 2, 1); err != nil {
+			t.Fatal(err)
+		}
+		x := engineTensor(t, e, std.Materialize().Data().([]float32), shape[0], shape[2], shape[1])
+		if err := x.T(0, 2, 1); err != nil {
+			t.Fatal(err)
+		}
+		return x
+	case "batch":
+		std := stdTensor(data, shape...)
+		if err := std.T(1, 0, 2); err != nil {
+			t.Fatal(err)
+		}
+		x := engineTensor(t, e, std.Materialize().Data().([]float32), shape[1], shape[0], shape[2])
+		if err := x.T(1, 0, 2); err != nil {
+			t.Fatal(err)
+		}
+		return x
+	}
+	return engineTensor(t, e, data, shape...)
+}
+
+func TestEngine_BatchedMatMul(t *testing.T) {
+	const batches, m, k, n = 3, 4, 5, 2
+	aData, bData := makeRandom(batches*m, k), makeRandom(batches*k, n)
+	layouts := []string{"row", "trans", "batch"}
+	for _, al := range layouts {
+		for _, bl := range layouts {
+			for _, cl := range layouts {
+				e := newTestEngine(t)
+				a := batchOf(t, e, aData, al, batches, m, k)
+				b := batchOf(t, e, bData, bl, batches, k, n)
+				c := batchOf(t, e, make([]float32, batches*m*n), cl, batches, m, n)
+				if err := e.BatchedMatMul(a, b, c); err != nil {
+					t.Fatal(err)
+				}
+				mc, err := e.Materialize(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := naiveBatchedMatMul(aData, bData, batches, m, k, n, false)
+				assert.True(t, allWithinRange(want, readback(t, e, mc).Data().([]float32), closef32), "a: %v, b: %v, c: %v", al, bl, cl)
+			}
+		}
+	}
+}
+
+func TestEngine_SharedMatMul(t *testing.T) {
+	const batches, m, k, n = 3, 4, 5, 2
+	aData, bData := makeRandom(batches*m, k), makeRandom(k, n)
+	for _, al := range []string{"row", "trans", "batch"} {
+		for _, transB := range []bool{false, true} {
+			e := newTestEngine(t)
+			a := batchOf(t, e, aData, al, batches, m, k)
+			b := engineTensor(t, e, bData, k, n)
+			if transB {
+				std := stdTensor(bData, k, n)
+				if err := std.T(); err != nil {
+					t.Fatal(err)
+				}
+				b = engineColMajor(t, e, std.Materialize().Data().([]float32), k, n)
+			}
+			c := engineTensor(t, e, make([]float32, batches*m*n), batches, m, n)
+			if err := e.BatchedMatMul(a, b, c); err != nil {
+				t.Fatal(err)
+			}
+			want := naiveBatchedMatMul(aData, bData, batches, m, k, n, true)
+			assert.True(t, allWithinRange(want, readback(t, e, c).Data().([]float32), closef32), "a: %v, transB: %t", al, transB)
+		}
+	}
+}
+
+func TestEngine_BatchedMatMulErrors(t *testing.T) {
+	e := newTestEngine(t)
+	zeros := func(shape ...int) *tensor.Dense {
+		return engineTensor(t, e, make([]float32, tensor.Shape(shape).TotalSize()), shape...)
+	}
+	cases := []struct {
+		name    string
+		a, b, c *tensor.Dense
+	}{
+		{"2D a", zeros(4, 5), zeros(5, 2), zeros(4, 2)},
+		{"batches", zeros(3, 4, 5), zeros(2, 5, 2), zeros(3, 4, 2)},
+		{"inner", zeros(3, 4, 5), zeros(3, 4, 2), zeros(3, 4, 2)},
+		{"result", zeros(3, 4, 5), zeros(5, 2), zeros(3, 2, 4)},
+		{"dtype", zeros(3, 4, 5), zeros(5, 2), tensor.New(tensor.WithShape(3, 4, 2), tensor.Of(tensor.Float64))},
+	}
+	for _, c := range cases {
+		assert.Error(t, e.BatchedMatMul(c.a, c.b, c.c), c.name)
+	}
+
+	// the temporary result, made as the batches of c aren't outermost, is released when the op fails, here as a is not in the memory of the Engine
+	c := zeros(4, 3, 2)
+	if err := c.T(1, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	live := liveAllocs(e)
+	err := e.BatchedMatMul(tensor.New(tensor.WithShape(3, 4, 5), tensor.Of(tensor.Float32)), zeros(3, 5, 2), c)
+	if assert.Error(t, err, "Go memory") {
+		assert.Regexp(t, `^BatchedMatMul\(\): `, err.Error())
+	}
+	assert.Equal(t, live+1, liveAllocs(e), "only b should be left")
+}
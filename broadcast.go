@@ -0,0 +1,83 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// broadcastShape returns the shape that tensors of shapes a and b broadcast to, following NumPy's rules:
+// the shapes are aligned on their trailing dimensions, and each pair of dimensions must either match or contain a 1.
+func broadcastShape(a, b tensor.Shape) (tensor.Shape, error) {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	retVal := make(tensor.Shape, n)
+	for i := 1; i <= n; i++ {
+		da, db := dimFromEnd(a, i), dimFromEnd(b, i)
+		switch {
+		case da == db || db == 1:
+			retVal[n-i] = da
+		case da == 1:
+			retVal[n-i] = db
+		default:
+			return nil, errors.Errorf("Cannot broadcast shapes %v and %v: dimension %d of a is %d, but dimension %d of b is %d", a, b, len(a)-i, da, len(b)-i, db)
+		}
+	}
+	return retVal, nil
+}
+
+// broadcastShapes returns the shape that tensors of all the shapes broadcast to.
+func broadcastShapes(shapes ...tensor.Shape) (retVal tensor.Shape, err error) {
+	retVal = tensor.ScalarShape()
+	for _, s := range shapes {
+		if retVal, err = broadcastShape(retVal, s); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
+
+// dimFromEnd returns the ith dimension of s counting from the end, treating missing leading dimensions as 1s.
+func dimFromEnd(s tensor.Shape, i int) int {
+	if i > len(s) {
+		return 1
+	}
+	return s[len(s)-i]
+}
+
+// broadcastStrides returns the strides, in elements, with which a tensor of the given shape and strides is read
+// when it is broadcast to the shape to. Broadcast dimensions have a stride of 0.
+func broadcastStrides(to, shape tensor.Shape, strides []int) []int {
+	retVal := make([]int, len(to))
+	lead := len(to) - len(shape)
+	for i := range shape {
+		if shape[i] != 1 {
+			retVal[lead+i] = strides[i]
+		}
+	}
+	return retVal
+}
+
+// newStridedLayout creates the layout with which a strided kernel iterates over the given shape.
+// result holds the strides of the result, and operands those of each operand. Unused ones are nil.
+func newStridedLayout(shape tensor.Shape, result []int, operands ...[]int) (l stridedLayout, err error) {
+	if len(shape) > maxDims {
+		return l, errors.Errorf("Strided kernels support up to %d dimensions. Got %v", maxDims, shape)
+	}
+	if len(operands) > maxOperands {
+		return l, errors.Errorf("Strided kernels support up to %d operands. Got %d", maxOperands, len(operands))
+	}
+	strides := append(make([][]int, maxOperands), result)
+	copy(strides, operands)
+	l.Dims = uint32(len(shape))
+	for d, dim := range shape {
+		l.Shape[d] = uint32(dim)
+		for k := range strides {
+			if strides[k] != nil {
+				l.Strides[k][d] = uint32(strides[k][d])
+			}
+		}
+	}
+	return l, nil
+}
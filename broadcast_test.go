@@ -0,0 +1,132 @@
+package magol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestBroadcastShape(t *testing.T) {
+	cases := []struct {
+		a, b, expected tensor.Shape
+	}{
+		{tensor.Shape{2, 3}, tensor.Shape{2, 3}, tensor.Shape{2, 3}},
+		{tensor.Shape{2, 3}, tensor.Shape{1, 3}, tensor.Shape{2, 3}},
+		{tensor.Shape{2, 3}, tensor.Shape{2, 1}, tensor.Shape{2, 3}},
+		{tensor.Shape{2, 3}, tensor.Shape{3}, tensor.Shape{2, 3}},
+		{tensor.Shape{3}, tensor.Shape{2, 3}, tensor.Shape{2, 3}},
+		{tensor.Shape{2, 1, 3}, tensor.Shape{4, 1}, tensor.Shape{2, 4, 3}},
+		{tensor.Shape{1}, tensor.Shape{5, 4}, tensor.Shape{5, 4}},
+	}
+	for _, c := range cases {
+		s, err := broadcastShape(c.a, c.b)
+		if err != nil {
+			t.Errorf("%v and %v: %v", c.a, c.b, err)
+			continue
+		}
+		assert.Equal(t, c.expected, s, "%v and %v", c.a, c.b)
+	}
+
+	_, err := broadcastShape(tensor.Shape{2, 3}, tensor.Shape{4, 3})
+	assert.EqualError(t, err, "Cannot broadcast shapes (2, 3) and (4, 3): dimension 0 of a is 2, but dimension 0 of b is 4")
+	_, err = broadcastShape(tensor.Shape{2, 3}, tensor.Shape{2})
+	assert.Error(t, err)
+
+	s, err := broadcastShapes(tensor.Shape{3, 1}, tensor.Shape{4}, tensor.Shape{2, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{2, 3, 4}, s)
+	_, err = broadcastShapes(tensor.Shape{3, 1}, tensor.Shape{4}, tensor.Shape{2})
+	assert.Error(t, err)
+}
+
+// expand materializes the broadcast of a Go tensor to shape.
+func expand(t *testing.T, x *tensor.Dense, shape tensor.Shape) *tensor.Dense {
+	retVal := x.Clone().(*tensor.Dense)
+	s := make(tensor.Shape, len(shape))
+	for i := range s {
+		s[i] = dimFromEnd(x.Shape(), len(shape)-i)
+	}
+	if err := retVal.Reshape(s...); err != nil {
+		t.Fatal(err)
+	}
+	for axis := range shape {
+		if s[axis] == 1 && shape[axis] != 1 {
+			r, err := tensor.Repeat(retVal, axis, shape[axis])
+			if err != nil {
+				t.Fatal(err)
+			}
+			retVal = r.(*tensor.Dense)
+		}
+	}
+	return retVal
+}
+
+func TestEngine_Broadcast(t *testing.T) {
+	cases := []struct {
+		a, b tensor.Shape
+	}{
+		{tensor.Shape{2, 3}, tensor.Shape{1, 3}}, // bias
+		{tensor.Shape{2, 3}, tensor.Shape{3}},
+		{tensor.Shape{2, 3}, tensor.Shape{2, 1}}, // row scaling
+		{tensor.Shape{2, 1}, tensor.Shape{1, 3}}, // outer
+		{tensor.Shape{3}, tensor.Shape{4, 2, 3}},
+		{tensor.Shape{2, 1, 3}, tensor.Shape{4, 1}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v%v", c.a, c.b), func(t *testing.T) {
+			assert := assert.New(t)
+			e := newTestEngine(t)
+			backingA := tensor.Range(tensor.Float32, 1, c.a.TotalSize()+1).([]float32)
+			backingB := tensor.Range(tensor.Float32, 2, c.b.TotalSize()+2).([]float32)
+			a := engineTensor(t, e, backingA, c.a...)
+			b := engineTensor(t, e, backingB, c.b...)
+
+			shape, err := broadcastShape(c.a, c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdA := expand(t, tensor.New(tensor.WithShape(c.a...), tensor.WithBacking(backingA)), shape)
+			stdB := expand(t, tensor.New(tensor.WithShape(c.b...), tensor.WithBacking(backingB)), shape)
+
+			sub, err := e.Sub(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := tensor.Sub(stdA, stdB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(shape.Eq(sub.Shape()), "Expected %v. Got %v", shape, sub.Shape())
+			assert.Equal(expected.Data(), readback(t, e, sub).Data())
+
+			mul, err := e.Mul(b, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected, err = tensor.Mul(stdB, stdA); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(expected.Data(), readback(t, e, mul).Data())
+		})
+	}
+}
+
+func TestEngine_BroadcastUnsafe(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	bias := engineTensor(t, e, []float32{10, 20, 30}, 1, 3)
+	c, err := e.Add(a, bias, tensor.UseUnsafe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, c == a)
+	assert.Equal(t, []float32{11, 22, 33, 14, 25, 36}, readback(t, e, a).Data())
+
+	// the result does not fit in bias
+	_, err = e.Add(bias, a, tensor.UseUnsafe())
+	assert.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package magol
+
+import (
+	"unsafe"
+)
+
+// Buffer is a memory slice owned by a Backend.
+//
+// For the Metal backend b is an id<MTLBuffer>. For the host backend b points to plain Go memory.
+// In both cases ptr is the address of the contents of the Buffer, which is what Uintptr returns.
+// A Buffer may be a view into another Buffer, starting off bytes into it.
+type Buffer struct {
+	b   unsafe.Pointer
+	ptr unsafe.Pointer
+	off uintptr
+	sz  uintptr
+}
+
+func (b Buffer) Uintptr() uintptr { return uintptr(b.ptr) }
+func (b Buffer) MemSize() uintptr { return b.sz }
+
+// view returns a Buffer of sz bytes, starting off bytes into b.
+func (b Buffer) view(off, sz uintptr) Buffer {
+	return Buffer{b: b.b, ptr: unsafe.Add(b.ptr, off), off: b.off + off, sz: sz}
+}
+
+// ptrOf converts a uintptr obtained from a tensor.Memory back into a pointer.
+//
+//go:nocheckptr
+func ptrOf(p uintptr) unsafe.Pointer { return *(*unsafe.Pointer)(unsafe.Pointer(&p)) }
+
+type byteslice []byte
+
+func (b byteslice) Uintptr() uintptr { return uintptr(unsafe.Pointer(&b[0])) }
+func (b byteslice) MemSize() uintptr { return uintptr(len(b)) }
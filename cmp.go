@@ -0,0 +1,157 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// The comparisons return Bools, unless they are given tensor.AsSameType, or are unsafe,
+// in which case they return 1 for true and 0 for false in the Dtype of their operands.
+// Bools can only be compared for equality.
+
+func (e *Engine) Gt(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("Gt()", "gt", a, b, opts...)
+}
+
+func (e *Engine) Gte(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("Gte()", "gte", a, b, opts...)
+}
+
+func (e *Engine) Lt(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("Lt()", "lt", a, b, opts...)
+}
+
+func (e *Engine) Lte(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("Lte()", "lte", a, b, opts...)
+}
+
+func (e *Engine) ElEq(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("ElEq()", "eq", a, b, opts...)
+}
+
+func (e *Engine) ElNe(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpOp("ElNe()", "ne", a, b, opts...)
+}
+
+func (e *Engine) GtScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("GtScalar()", "gt", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) GteScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("GteScalar()", "gte", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) LtScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("LtScalar()", "lt", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) LteScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("LteScalar()", "lte", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) EqScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("EqScalar()", "eq", a, b, leftTensor, opts...)
+}
+
+func (e *Engine) NeScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.cmpScalarOp("NeScalar()", "ne", a, b, leftTensor, opts...)
+}
+
+// cmpOp runs the kernel of the comparison op elementwise over a and b, broadcasting them against each other.
+// fn is the name of the calling method, for errors.
+func (e *Engine) cmpOp(fn, op string, a, b tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdCmpOps[op](std, a, b, opts...)
+		return err
+	}, a, b); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.cmpOp(fn, op, a, b, sameTypeOpts(opts)...) })
+	}
+	if err = e.checkValidDtype(a, b); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = checkCmpDtype(op, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	shape, err := broadcastShape(a.Shape(), b.Shape())
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	result := cmpResultOf(a, opts)
+	kernel := func(strided bool) (string, error) { return cmpName(op, false, false, strided, result, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if retVal, err = e.prepResultOf(a, shape, result, opts...); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.runElementwise(kernel, shape, retVal, a, b); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
+
+// cmpScalarOp runs the scalar kernel of the comparison op elementwise over a, with b as the other operand.
+// leftTensor indicates if a is the left operand. fn is the name of the calling method, for errors.
+func (e *Engine) cmpScalarOp(fn, op string, a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdCmpScalarOps[op](std, a, b, leftTensor, opts...)
+		return err
+	}, a); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) {
+			return e.cmpScalarOp(fn, op, a, b, leftTensor, sameTypeOpts(opts)...)
+		})
+	}
+	scalar, err := e.checkValidScalar(a, b)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = checkCmpDtype(op, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	result := cmpResultOf(a, opts)
+	kernel := func(strided bool) (string, error) { return cmpName(op, true, leftTensor, strided, result, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if retVal, err = e.prepResultOf(a, a.Shape(), result, opts...); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.runScalar(kernel, a, retVal, scalar); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
+
+// checkCmpDtype checks that the comparison op has kernels for operands of dt, which Bools only have for equality.
+func checkCmpDtype(op string, dt tensor.Dtype) error {
+	for _, c := range cmpOps {
+		if c.Name == op && (dt != tensor.Bool || c.Bools) {
+			return nil
+		}
+	}
+	return errors.Errorf("Cannot compare %vs with %v", dt, op)
+}
+
+// cmpResultOf returns the Dtype of the result of comparing a as opts dictate: that of a if it is tensor.AsSameType or unsafe, Bool otherwise.
+func cmpResultOf(a tensor.Tensor, opts []tensor.FuncOpt) tensor.Dtype {
+	if fo := tensor.ParseFuncOpts(opts...); fo.Same() || !fo.Safe() {
+		return a.Dtype()
+	}
+	return tensor.Bool
+}
+
+// sameTypeOpts returns the tensor.AsSameType option if opts have it, for comparisons whose results are accumulated WithIncr.
+func sameTypeOpts(opts []tensor.FuncOpt) []tensor.FuncOpt {
+	if tensor.ParseFuncOpts(opts...).Same() {
+		return []tensor.FuncOpt{tensor.AsSameType()}
+	}
+	return nil
+}
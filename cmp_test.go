@@ -0,0 +1,217 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_Cmp(t *testing.T) {
+	backingA := []float32{1.5, 2, 3, 4.25, 5, 6}
+	backingB := []float32{2, 2, 3, 1.5, 4, 7}
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std  func(e tensor.StdEng, a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"Gt", (*Engine).Gt, tensor.StdEng.Gt},
+		{"Gte", (*Engine).Gte, tensor.StdEng.Gte},
+		{"Lt", (*Engine).Lt, tensor.StdEng.Lt},
+		{"Lte", (*Engine).Lte, tensor.StdEng.Lte},
+		{"ElEq", (*Engine).ElEq, tensor.StdEng.ElEq},
+		{"ElNe", (*Engine).ElNe, tensor.StdEng.ElNe},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			e := newTestEngine(t)
+			a, b := engineTensor(t, e, backingA, 2, 3), engineTensor(t, e, backingB, 2, 3)
+			ha := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backingA...)))
+			hb := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backingB...)))
+
+			want, err := op.std(tensor.StdEng{}, ha, hb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := op.eng(e, a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tensor.Bool, got.Dtype())
+			assert.Equal(t, want.Data(), readback(t, e, got).Data())
+
+			want, err = op.std(tensor.StdEng{}, ha, hb, tensor.AsSameType())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = op.eng(e, a, b, tensor.AsSameType())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tensor.Float32, got.Dtype())
+			assert.Equal(t, want.Data(), readback(t, e, got).Data(), "AsSameType")
+
+			// a row of b is broadcast
+			row, err := b.Slice(tensor.S(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hrow := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float32{1.5, 4, 7, 1.5, 4, 7}))
+			want, err = op.std(tensor.StdEng{}, ha, hrow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = op.eng(e, a, row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want.Data(), readback(t, e, got).Data(), "broadcast")
+
+			// the reuse tensor must be of the Dtype of the result
+			reuse := engineTensorOf(t, e, tensor.Bool, 2, 3)
+			if got, err = op.eng(e, a, b, tensor.WithReuse(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, got == reuse, "the result should go into the reuse tensor")
+			_, err = op.eng(e, a, b, tensor.WithReuse(engineTensorOf(t, e, tensor.Float32, 2, 3)))
+			assert.Error(t, err, "a Float32 reuse tensor for a Bool result")
+
+			// unsafe ops write 0 or 1 into a
+			want, err = op.std(tensor.StdEng{}, ha, hb, tensor.AsSameType())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err = op.eng(e, a, b, tensor.UseUnsafe()); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, got == a, "unsafe ops should write into a")
+			assert.Equal(t, want.Data(), readback(t, e, a).Data(), "unsafe")
+		})
+	}
+}
+
+func TestEngine_CmpScalar(t *testing.T) {
+	backing := []float32{1, 2, 3, 4, 5, 6}
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std  func(e tensor.StdEng, a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"GtScalar", (*Engine).GtScalar, tensor.StdEng.GtScalar},
+		{"GteScalar", (*Engine).GteScalar, tensor.StdEng.GteScalar},
+		{"LtScalar", (*Engine).LtScalar, tensor.StdEng.LtScalar},
+		{"LteScalar", (*Engine).LteScalar, tensor.StdEng.LteScalar},
+		{"EqScalar", (*Engine).EqScalar, tensor.StdEng.EqScalar},
+		{"NeScalar", (*Engine).NeScalar, tensor.StdEng.NeScalar},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			e := newTestEngine(t)
+			a := engineTensor(t, e, backing, 2, 3)
+			ha := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backing...)))
+			for _, leftTensor := range []bool{true, false} {
+				for _, same := range []bool{false, true} {
+					var opts []tensor.FuncOpt
+					if same {
+						opts = append(opts, tensor.AsSameType())
+					}
+					want, err := op.std(tensor.StdEng{}, ha, float32(3), leftTensor, opts...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := op.eng(e, a, float32(3), leftTensor, opts...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.Equal(t, want.Dtype(), got.Dtype(), "leftTensor %v, AsSameType %v", leftTensor, same)
+					assert.Equal(t, want.Data(), readback(t, e, got).Data(), "leftTensor %v, AsSameType %v", leftTensor, same)
+				}
+			}
+
+			// transposed views use the strided kernels
+			if err := a.T(); err != nil {
+				t.Fatal(err)
+			}
+			if err := ha.T(); err != nil {
+				t.Fatal(err)
+			}
+			want, err := op.std(tensor.StdEng{}, ha, float32(3), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := op.eng(e, a, float32(3), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want.Data(), readback(t, e, got).Data(), "transposed")
+
+			_, err = op.eng(e, a, 3.0, true)
+			assert.Error(t, err, "a float64 scalar")
+		})
+	}
+}
+
+func TestEngine_CmpDtypes(t *testing.T) {
+	e := newTestEngine(t)
+
+	// a ReLU mask and the count of correct predictions, in the ways they are made from comparisons
+	x := engineTensor(t, e, []float32{-1, 2, 0, 3}, 4)
+	mask, err := e.GtScalar(x, float32(0), true, tensor.AsSameType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	relu, err := e.Mul(x, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{0, 2, 0, 3}, readback(t, e, relu).Data())
+	labels := engineTensor(t, e, []float32{0, 2, 1, 3}, 4)
+	correct := engineTensor(t, e, []float32{1, 1, 1, 1}, 4)
+	if _, err = e.ElEq(x, labels, tensor.AsSameType(), tensor.WithIncr(correct)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{1, 2, 1, 2}, readback(t, e, correct).Data(), "WithIncr")
+
+	for _, dt := range append([]tensor.Dtype{Float16}, intDtypes...) {
+		var a, b *tensor.Dense
+		if isInt(dt) {
+			a = engineCopy(t, e, tensor.New(tensor.WithBacking(intsOf([]int64{1, 5, 3}, dt))))
+			b = engineCopy(t, e, tensor.New(tensor.WithBacking(intsOf([]int64{2, 5, 1}, dt))))
+		} else {
+			a, b = engineHalfTensor(t, e, dt, []float32{1, 5, 3}, 3), engineHalfTensor(t, e, dt, []float32{2, 5, 1}, 3)
+		}
+		lt, err := e.Lt(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []bool{true, false, false}, readback(t, e, lt).Data(), "%v", dt)
+		gte, err := e.Gte(a, b, tensor.AsSameType())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, dt, gte.Dtype())
+		if isInt(dt) {
+			assert.Equal(t, intsOf([]int64{0, 1, 1}, dt), readback(t, e, gte).Data(), "%v", dt)
+		} else {
+			assert.Equal(t, []float32{0, 1, 1}, readbackFloats(t, e, gte), "%v", dt)
+		}
+	}
+
+	p := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, false, true})))
+	q := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, true, false})))
+	ne, err := e.ElNe(p, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []bool{false, true, true}, readback(t, e, ne).Data())
+	eq, err := e.EqScalar(p, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []bool{true, false, true}, readback(t, e, eq).Data())
+	_, err = e.Gt(p, q)
+	assert.Error(t, err, "Bools are only compared for equality")
+
+	_, err = e.Gt(x, p)
+	assert.Error(t, err, "mixing dtypes")
+}
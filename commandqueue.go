@@ -1,6 +1,12 @@
+//go:build darwin
+// +build darwin
+
 package magol
 
-import "unsafe"
+import (
+	"runtime/cgo"
+	"unsafe"
+)
 
 /*
 #cgo LDFLAGS: -framework Metal -framework CoreGraphics -framework Foundation
@@ -21,10 +27,38 @@ type CommandBuffer struct {
 func MakeCommandQueue(dev *Device) CommandQueue     { return CommandQueue{C.MakeCommandQueue(dev.d)} }
 func (q CommandQueue) CommandBuffer() CommandBuffer { return CommandBuffer{C.MakeCommandBuffer(q.q)} }
 func (b CommandBuffer) Enqueue()                    { C.CmdBuf_Enqueue(b.b) }
+func (b CommandBuffer) WaitUntilCompleted()         { C.CmdBuf_WaitUntilCompleted(b.b) }
 func (b CommandBuffer) MakeComputeCommandEncoder() ComputeCommandEncoder {
 	return ComputeCommandEncoder{CommandEncoder{C.MakeComputeCommandEncoder(b.b)}}
 }
 
+// Commit commits the command buffer for execution. The returned Completion is done when the GPU has finished executing it.
+//
+// See: https://developer.apple.com/documentation/metal/mtlcommandbuffer/1443003-commit?language=objc
+func (b CommandBuffer) Commit() *Completion {
+	c := newCompletion()
+	c.setStatus(StatusCommitted)
+	C.CmdBuf_Commit(b.b, C.uint64_t(cgo.NewHandle(c)))
+	return c
+}
+
+//export magolCommandBufferScheduled
+func magolCommandBufferScheduled(handle C.uint64_t) {
+	cgo.Handle(handle).Value().(*Completion).setStatus(StatusScheduled)
+}
+
+//export magolCommandBufferCompleted
+func magolCommandBufferCompleted(handle C.uint64_t, status C.int, code C.long, msg *C.char) {
+	h := cgo.Handle(handle)
+	c := h.Value().(*Completion)
+	h.Delete()
+	if CommandBufferStatus(status) == StatusError {
+		c.complete(&CommandBufferError{Code: CommandBufferErrorCode(code), Msg: C.GoString(msg)})
+		return
+	}
+	c.complete(nil)
+}
+
 type CommandEncoder struct {
 	e unsafe.Pointer
 }
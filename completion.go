@@ -0,0 +1,132 @@
+package magol
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CommandBufferStatus is the status of a committed batch of work.
+//
+// See: https://developer.apple.com/documentation/metal/mtlcommandbufferstatus?language=objc
+type CommandBufferStatus int
+
+const (
+	StatusNotEnqueued CommandBufferStatus = iota // The command buffer is not enqueued yet.
+	StatusEnqueued                               // The command buffer is enqueued.
+	StatusCommitted                              // The command buffer is committed for execution.
+	StatusScheduled                              // The command buffer is scheduled.
+	StatusCompleted                              // The command buffer completed execution successfully.
+	StatusError                                  // Execution of the command buffer was aborted due to an error during execution.
+)
+
+func (s CommandBufferStatus) String() string {
+	switch s {
+	case StatusNotEnqueued:
+		return "NotEnqueued"
+	case StatusEnqueued:
+		return "Enqueued"
+	case StatusCommitted:
+		return "Committed"
+	case StatusScheduled:
+		return "Scheduled"
+	case StatusCompleted:
+		return "Completed"
+	case StatusError:
+		return "Error"
+	}
+	return fmt.Sprintf("CommandBufferStatus(%d)", int(s))
+}
+
+// CommandBufferErrorCode is the reason a command buffer failed.
+//
+// See: https://developer.apple.com/documentation/metal/mtlcommandbuffererror/code?language=objc
+type CommandBufferErrorCode int
+
+const (
+	ErrCodeNone            CommandBufferErrorCode = 0  // No error.
+	ErrCodeInternal        CommandBufferErrorCode = 1  // An internal error that doesn't fit into the other categories.
+	ErrCodeTimeout         CommandBufferErrorCode = 2  // Execution of this command buffer took too long.
+	ErrCodePageFault       CommandBufferErrorCode = 3  // Execution of this command generated an unserviceable GPU page fault.
+	ErrCodeAccessRevoked   CommandBufferErrorCode = 4  // Access to this device has been revoked.
+	ErrCodeNotPermitted    CommandBufferErrorCode = 7  // The process doesn't have access to the GPU device.
+	ErrCodeOutOfMemory     CommandBufferErrorCode = 8  // Insufficient memory was available to execute the command buffer.
+	ErrCodeInvalidResource CommandBufferErrorCode = 9  // The command buffer referenced an invalid resource.
+	ErrCodeMemoryless      CommandBufferErrorCode = 10 // A memoryless render target's memory was exhausted.
+	ErrCodeDeviceRemoved   CommandBufferErrorCode = 11 // The device was removed during execution.
+	ErrCodeStackOverflow   CommandBufferErrorCode = 12 // The call stack of a kernel overflowed.
+)
+
+// CommandBufferError is the error reported by the device when a committed batch of work fails.
+type CommandBufferError struct {
+	Code CommandBufferErrorCode
+	Msg  string
+}
+
+func (e *CommandBufferError) Error() string {
+	return fmt.Sprintf("Command buffer failed with code %d: %s", int(e.Code), e.Msg)
+}
+
+// Completion is a handle on a committed batch of work. It is completed when the device has finished
+// executing the batch, successfully or not.
+type Completion struct {
+	sync.Mutex
+	status CommandBufferStatus
+	err    error
+	done   chan struct{}
+}
+
+func newCompletion() *Completion {
+	return &Completion{done: make(chan struct{})}
+}
+
+// completedCompletion returns a Completion for a batch with no work in it.
+func completedCompletion() *Completion {
+	c := newCompletion()
+	c.complete(nil)
+	return c
+}
+
+// Wait blocks until the batch of work has completed, and returns the error it failed with, if any.
+func (c *Completion) Wait() error {
+	<-c.done
+	return c.err
+}
+
+// Done returns a channel that is closed when the batch of work has completed.
+func (c *Completion) Done() <-chan struct{} { return c.done }
+
+// Status returns the current status of the batch of work.
+func (c *Completion) Status() CommandBufferStatus {
+	c.Lock()
+	defer c.Unlock()
+	return c.status
+}
+
+// Err returns the error the batch of work failed with. It is nil until the batch has completed.
+func (c *Completion) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Completion) setStatus(s CommandBufferStatus) {
+	c.Lock()
+	c.status = s
+	c.Unlock()
+}
+
+// complete marks the batch of work as done. It must be called exactly once.
+func (c *Completion) complete(err error) {
+	c.Lock()
+	c.err = err
+	if err != nil {
+		c.status = StatusError
+	} else {
+		c.status = StatusCompleted
+	}
+	c.Unlock()
+	close(c.done)
+}
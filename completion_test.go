@@ -0,0 +1,94 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestCompletion(t *testing.T) {
+	assert := assert.New(t)
+	hb := NewHostBackend()
+
+	// an empty batch is immediately complete
+	c := hb.Commit()
+	assert.Equal(StatusCompleted, c.Status())
+	assert.NoError(c.Wait())
+
+	// a batch is held back until it is committed
+	block := make(chan struct{})
+	var ran []int
+	hb.enqueue(func() { <-block; ran = append(ran, 1) })
+	first := hb.Commit()
+	hb.enqueue(func() { ran = append(ran, 2) })
+	second := hb.Commit()
+
+	select {
+	case <-second.Done():
+		t.Fatal("second batch completed before the first one")
+	default:
+	}
+	assert.Nil(second.Err())
+
+	close(block)
+	assert.NoError(second.Wait())
+	assert.Equal(StatusCompleted, first.Status())
+	assert.Equal(StatusCompleted, second.Status())
+	assert.Equal([]int{1, 2}, ran, "batches must run in commit order")
+}
+
+func TestCompletion_Error(t *testing.T) {
+	hb := NewHostBackend()
+	hb.enqueue(func() { panic("kernel failed") })
+	c := hb.Commit()
+	err := c.Wait()
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Equal(t, StatusError, c.Status())
+	cbErr, ok := err.(*CommandBufferError)
+	if !assert.True(t, ok, "expected a *CommandBufferError. Got %T instead", err) {
+		return
+	}
+	assert.Equal(t, ErrCodeInternal, cbErr.Code)
+	assert.Equal(t, "kernel failed", cbErr.Msg)
+}
+
+func TestEngine_Commit(t *testing.T) {
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb)
+
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := engineTensor(t, e, []float32{1, 1, 1}, 3)
+	if _, err := e.Add(a, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	c := e.Commit()
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCompleted, c.Status())
+
+	// Sync reports errors from batches that were committed earlier
+	hb.enqueue(func() { panic("kernel failed") })
+	e.q.pending++
+	e.Commit()
+	assert.Error(t, e.Sync())
+	assert.NoError(t, e.Sync())
+	assert.Equal(t, []float32{2, 3, 4}, readback(t, e, a).Data())
+
+	// batches that are empty or done are not kept around for a Sync that never comes
+	for i := 0; i < 10; i++ {
+		e.Commit()
+		if _, err := e.Add(a, b, tensor.UseUnsafe()); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Commit().Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.q.Lock()
+	assert.LessOrEqual(t, len(e.q.inflight), 1)
+	e.q.Unlock()
+}
@@ -0,0 +1,224 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Outer adds the outer product of the vectors a and b to prealloc, which must be of shape (len(a), len(b)).
+// Like tensor.StdEng's, it accumulates: tensor.Outer zeroes prealloc before calling it.
+func (e *Engine) Outer(a, b, prealloc tensor.Tensor) error {
+	if ok, err := e.onHost(func(std tensor.StdEng) error { return std.Outer(a, b, prealloc) }, a, b, prealloc); ok {
+		return errors.Wrap(err, "Outer()")
+	}
+	if err := e.outer(a, b, prealloc); err != nil {
+		return errors.Wrap(err, "Outer()")
+	}
+	return nil
+}
+
+func (e *Engine) outer(a, b, prealloc tensor.Tensor) error {
+	ad, bd, retVal, err := e.checkValidVectors(a, b, prealloc)
+	if err != nil {
+		return err
+	}
+	m, n := getVecLen(ad.Shape()), getVecLen(bd.Shape())
+	if retVal.Shape().Dims() != 2 || retVal.Shape()[0] != m || retVal.Shape()[1] != n {
+		return errors.Errorf("Expected prealloc to be of shape %v. Got %v instead", tensor.Shape{m, n}, retVal.Shape())
+	}
+
+	ad, al, err := e.vectorOperand(ad)
+	if err != nil {
+		return err
+	}
+	if ad != a {
+		defer e.release(ad)
+	}
+	bd, bl, err := e.vectorOperand(bd)
+	if err != nil {
+		return err
+	}
+	if bd != b {
+		defer e.release(bd)
+	}
+	cd, cl, transC, err := e.matrixResult(retVal, true)
+	if err != nil {
+		return err
+	}
+	if cd != retVal {
+		// the temporary result is accumulated into
+		if err = e.copyInto(cd, retVal); err != nil {
+			e.discardResult(retVal, cd)
+			return err
+		}
+	}
+
+	bufs, err := e.buffersOf(ad, bd, cd)
+	if err != nil {
+		e.discardResult(retVal, cd)
+		return err
+	}
+	// a ⊗ b is the product of a as a single column, which is a transposed single row, and b as a single row
+	if err = e.encodeMatMul(bufs, rowLayout(al), rowLayout(bl), cl, true, false, transC, 1, 1); err != nil {
+		e.discardResult(retVal, cd)
+		return err
+	}
+	return e.finishResult(retVal, cd)
+}
+
+// Inner returns the inner product of the vectors a and b, as a value of their Dtype. It waits for the product to be computed.
+func (e *Engine) Inner(a, b tensor.Tensor) (interface{}, error) {
+	var ip interface{}
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		ip, err = std.Inner(a, b)
+		return err
+	}, a, b); ok {
+		return ip, errors.Wrap(err, "Inner()")
+	}
+	ret, err := e.makeTensor(tensor.ScalarShape(), a.Dtype())
+	if err != nil {
+		return nil, errors.Wrap(err, "Inner()")
+	}
+	defer e.release(ret)
+	if err = e.inner(a, b, ret); err != nil {
+		return nil, errors.Wrap(err, "Inner()")
+	}
+
+	retVal := tensor.New(tensor.Of(ret.Dtype()), tensor.WithShape(1))
+	if err = e.Memcpy(retVal, ret); err != nil {
+		return nil, errors.Wrap(err, "Inner()")
+	}
+	return retVal.Get(0), nil
+}
+
+// inner computes the inner product of the vectors a and b into the single element tensor ret.
+func (e *Engine) inner(a, b, ret tensor.Tensor) error {
+	ad, bd, retVal, err := e.checkValidVectors(a, b, ret)
+	if err != nil {
+		return err
+	}
+	if getVecLen(ad.Shape()) != getVecLen(bd.Shape()) {
+		return errors.Errorf("Expected the vectors to be of the same length. Got %v and %v", ad.Shape(), bd.Shape())
+	}
+	if retVal.Shape().TotalSize() != 1 {
+		return errors.Errorf("Expected a single element result. Got %v instead", retVal.Shape())
+	}
+
+	ad, al, err := e.vectorOperand(ad)
+	if err != nil {
+		return err
+	}
+	if ad != a {
+		defer e.release(ad)
+	}
+	bd, bl, err := e.vectorOperand(bd)
+	if err != nil {
+		return err
+	}
+	if bd != b {
+		defer e.release(bd)
+	}
+
+	bufs, err := e.buffersOf(ad, bd, retVal)
+	if err != nil {
+		return err
+	}
+	// a·b is the matrix vector product of a as a single row and b
+	return e.encode(func() error {
+		return e.b.MatVecMul(bufs[0], bufs[1], bufs[2], rowLayout(al), bl, VectorLayout{Length: 1, Dtype: retVal.Dtype()}, false)
+	})
+}
+
+// Dot multiplies x and y as tensor.Dot does, choosing the product by the ranks of the operands:
+// scalars are multiplied elementwise, two vectors give their inner product as a scalar,
+// and matrices and vectors are multiplied by MatMul, MatVecMul, or MatVecMul of the transposed matrix for a vector and a matrix.
+// Tensors of higher ranks are not supported.
+//
+// The result goes into the tensor.WithReuse tensor if there is one, and is added to the tensor.WithIncr tensor if there is one.
+func (e *Engine) Dot(x, y tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.Dot(x, y, opts...)
+		return err
+	}, x, y); ok {
+		return retVal, errors.Wrap(err, "Dot()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.Dot(x, y) })
+	}
+	if err = e.checkValidDtype(x, y); err != nil {
+		return nil, errors.Wrap(err, "Dot()")
+	}
+	xs, ys := x.Shape(), y.Shape()
+	if xs.IsScalar() || ys.IsScalar() {
+		return e.Mul(x, y, opts...)
+	}
+	if err = e.checkLinalgDtype(x, y); err != nil {
+		return nil, errors.Wrap(err, "Dot()")
+	}
+
+	var shape tensor.Shape
+	var op func(ret tensor.Tensor) error
+	switch {
+	case xs.IsVector() && ys.IsVector():
+		shape = tensor.ScalarShape()
+		op = func(ret tensor.Tensor) error { return e.inner(x, y, ret) }
+	case xs.IsVector() && ys.IsMatrix():
+		shape = tensor.Shape{ys[1]}
+		op = func(ret tensor.Tensor) error { return e.gemv(true, y, x, ret) }
+	case xs.IsMatrix() && ys.IsVector():
+		shape = tensor.Shape{xs[0]}
+		op = func(ret tensor.Tensor) error { return e.gemv(false, x, y, ret) }
+	case xs.IsMatrix() && ys.IsMatrix():
+		shape = tensor.Shape{xs[0], ys[1]}
+		op = func(ret tensor.Tensor) error { return e.gemm(false, false, 1, x, y, 0, ret) }
+	default:
+		return nil, errors.Errorf("Dot(): Tensors of shapes %v and %v are not supported", xs, ys)
+	}
+
+	reuse, _, _, _, err := e.handleFuncOpts(shape, x.Dtype(), x.DataOrder(), opts...)
+	switch {
+	case err != nil:
+		return nil, errors.Wrap(err, "Dot()")
+	case reuse != nil:
+		retVal = reuse
+	default:
+		if retVal, err = e.makeTensor(shape, x.Dtype()); err != nil {
+			return nil, errors.Wrap(err, "Dot()")
+		}
+	}
+	if err = op(retVal); err != nil {
+		if retVal != reuse {
+			e.release(retVal)
+		}
+		return nil, errors.Wrap(err, "Dot()")
+	}
+	return retVal, nil
+}
+
+// checkValidVectors checks that a and b are vectorlike DenseTensors, and that ret is a DenseTensor, all of the same Dtype.
+func (e *Engine) checkValidVectors(a, b, ret tensor.Tensor) (ad, bd, retVal tensor.DenseTensor, err error) {
+	if err = e.checkLinalgDtype(a, b, ret); err != nil {
+		return nil, nil, nil, err
+	}
+	ad, ok := a.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected a to be a DenseTensor")
+	}
+	bd, ok = b.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected b to be a DenseTensor")
+	}
+	retVal, ok = ret.(tensor.DenseTensor)
+	if !ok {
+		return nil, nil, nil, errors.New("Expected retVal to be a DenseTensor")
+	}
+	if !ad.Shape().IsVectorLike() || !bd.Shape().IsVectorLike() {
+		return nil, nil, nil, errors.Errorf("Expected a and b to be vectorlike. Got %v and %v", ad.Shape(), bd.Shape())
+	}
+	return ad, bd, retVal, nil
+}
+
+// rowLayout returns the layout of the contiguous vector described by l as a matrix of a single row.
+func rowLayout(l VectorLayout) MatrixLayout {
+	return MatrixLayout{Rows: 1, Cols: l.Length, RowBytes: l.Length * int(l.Dtype.Size()), Dtype: l.Dtype}
+}
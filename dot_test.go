@@ -0,0 +1,184 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_Outer(t *testing.T) {
+	aData, bData := []float32{1, 2, 3}, []float32{4, 5}
+	want := []float32{4, 5, 8, 10, 12, 15}
+
+	e := newTestEngine(t)
+	a, b := engineTensor(t, e, aData, 3), engineTensor(t, e, bData, 2)
+	c, err := tensor.Outer(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want, readback(t, e, c).Data())
+
+	// Outer accumulates, into transposed results too
+	ct := engineTensor(t, e, []float32{1, 1, 1, 1, 1, 1}, 2, 3)
+	if err = ct.T(); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Outer(a, b, ct); err != nil {
+		t.Fatal(err)
+	}
+	m, err := e.Materialize(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{5, 6, 9, 11, 13, 16}, readback(t, e, m).Data())
+
+	// strided vectors
+	big := engineTensor(t, e, []float32{1, 0, 2, 0, 3, 0}, 3, 2)
+	av, err := big.Slice(nil, tensor.S(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2 := engineTensor(t, e, make([]float32, 6), 3, 2)
+	if err = e.Outer(av, b, c2); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want, readback(t, e, c2).Data())
+
+	assert.Error(t, e.Outer(a, b, engineTensor(t, e, make([]float32, 6), 3, 2, 1)))
+	assert.Error(t, e.Outer(a, c2, engineTensor(t, e, make([]float32, 18), 3, 6)))
+
+	// the temporary result, made as the result is strided along both axes, is released when the op fails, here as a is not in the memory of the Engine
+	strided, err := engineTensor(t, e, make([]float32, 12), 3, 4).Slice(nil, tensor.S(0, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := liveAllocs(e)
+	assert.Error(t, e.Outer(tensor.New(tensor.WithShape(3), tensor.Of(tensor.Float32)), b, strided), "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the temporary result should have been released")
+}
+
+func TestEngine_Inner(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := engineTensor(t, e, []float32{4, 5, 6}, 1, 3)
+	ip, err := tensor.Inner(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, float32(32), ip)
+
+	_, err = e.Inner(a, engineTensor(t, e, []float32{1, 2}, 2))
+	assert.Error(t, err)
+}
+
+// floats returns the data of a Float32 tensor as a slice, even if it is a scalar.
+func floats(data interface{}) []float32 {
+	if f, ok := data.(float32); ok {
+		return []float32{f}
+	}
+	return data.([]float32)
+}
+
+func TestEngine_Dot(t *testing.T) {
+	cases := []struct {
+		name   string
+		xs, ys tensor.Shape
+	}{
+		{"scalar·matrix", tensor.ScalarShape(), tensor.Shape{2, 3}},
+		{"vector·scalar", tensor.Shape{3}, tensor.ScalarShape()},
+		{"vector·vector", tensor.Shape{3}, tensor.Shape{3}},
+		{"row vector·column vector", tensor.Shape{1, 3}, tensor.Shape{3, 1}},
+		{"vector·matrix", tensor.Shape{3}, tensor.Shape{3, 4}},
+		{"matrix·vector", tensor.Shape{4, 3}, tensor.Shape{3}},
+		{"matrix·column vector", tensor.Shape{4, 3}, tensor.Shape{3, 1}},
+		{"matrix·matrix", tensor.Shape{4, 3}, tensor.Shape{3, 2}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			xData := makeRandom(1, c.xs.TotalSize())
+			yData := makeRandom(1, c.ys.TotalSize())
+			expected, err := tensor.Dot(stdTensor(xData, c.xs...), stdTensor(yData, c.ys...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := floats(expected.Data())
+
+			e := newTestEngine(t)
+			x, y := engineTensor(t, e, xData, c.xs...), engineTensor(t, e, yData, c.ys...)
+			ret, err := tensor.Dot(x, y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, expected.Shape(), ret.Shape())
+			assert.True(t, allWithinRange(want, floats(readback(t, e, ret).Data()), closef32))
+
+			// into a reused tensor
+			reuse := engineTensor(t, e, make([]float32, len(want)), expected.Shape()...)
+			if ret, err = tensor.Dot(x, y, tensor.WithReuse(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, ret == reuse, "expected the result to be the reused tensor")
+			assert.True(t, allWithinRange(want, floats(readback(t, e, reuse).Data()), closef32))
+
+			// added to an incr tensor
+			if ret, err = tensor.Dot(x, y, tensor.WithIncr(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, ret == reuse, "expected the result to be the incr tensor")
+			for i := range want {
+				want[i] *= 2
+			}
+			assert.True(t, allWithinRange(want, floats(readback(t, e, reuse).Data()), closef32))
+		})
+	}
+}
+
+func TestEngine_DotErrors(t *testing.T) {
+	e := newTestEngine(t)
+	m := engineTensor(t, e, make([]float32, 6), 2, 3)
+	v := engineTensor(t, e, make([]float32, 2), 2)
+	_, err := e.Dot(v, engineTensor(t, e, make([]float32, 3), 3))
+	assert.Error(t, err)
+	_, err = e.Dot(m, v)
+	assert.Error(t, err)
+	_, err = e.Dot(engineTensor(t, e, make([]float32, 4), 2, 2, 1), m)
+	assert.Error(t, err)
+	_, err = e.Dot(m, engineTensor(t, e, make([]float32, 3), 3), tensor.WithReuse(engineTensor(t, e, make([]float32, 3), 3)))
+	assert.Error(t, err)
+}
+
+func TestEngine_MatVecMul(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	x := engineTensor(t, e, []float32{1, 1, 2}, 3)
+	y := engineTensor(t, e, make([]float32, 2), 2)
+	if err := e.MatVecMul(a, x, y); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{9, 21}, readback(t, e, y).Data())
+
+	// a transposed view, into a column vector
+	if err := a.T(); err != nil {
+		t.Fatal(err)
+	}
+	yc := engineTensor(t, e, make([]float32, 3), 3, 1)
+	if err := e.MatVecMul(a, engineTensor(t, e, []float32{1, 2}, 1, 2), yc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{9, 12, 15}, readback(t, e, yc).Data())
+
+	// errors are returned
+	assert.Error(t, e.MatVecMul(a, x, y))
+	assert.Error(t, e.MatVecMul(a, engineTensor(t, e, make([]float32, 4), 2, 2), yc))
+	assert.Error(t, e.MatVecMul(a, engineTensor(t, e, []float32{1, 2}, 2), y))
+
+	// the temporary result, made as y is strided, is released when the op fails, here as A is not in the memory of the Engine
+	ys, err := engineTensor(t, e, make([]float32, 4), 4).Slice(tensor.S(0, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := liveAllocs(e)
+	assert.Error(t, e.MatVecMul(tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)), x, ys), "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the temporary result should have been released")
+}
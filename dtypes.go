@@ -0,0 +1,123 @@
+package magol
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// F16 is an IEEE 754 half precision float, the element type of Float16 tensors.
+type F16 struct{ bits uint16 }
+
+// BF16 is a bfloat16, the upper half of a float32, the element type of BFloat16 tensors.
+type BF16 struct{ bits uint16 }
+
+// The half precision Dtypes. They are registered with tensor as floats.
+var (
+	Float16  = tensor.Dtype{Type: reflect.TypeOf(F16{})}
+	BFloat16 = tensor.Dtype{Type: reflect.TypeOf(BF16{})}
+)
+
+func init() {
+	tensor.RegisterFloat(Float16)
+	tensor.RegisterFloat(BFloat16)
+}
+
+// NewF16 returns f rounded to the nearest F16, with ties to even.
+// Values too large for an F16 become infinities, and NaNs stay NaNs.
+func NewF16(f float32) F16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	man := b & 0x7fffff
+	switch {
+	case b&0x7fffffff > 0x7f800000:
+		// NaNs keep the top of their payload, and are made quiet so that it isn't all 0s
+		return F16{sign | 0x7e00 | uint16(man>>13)}
+	case exp >= 0x1f:
+		return F16{sign | 0x7c00}
+	case exp <= 0:
+		// subnormal: the value is m·2⁻²⁴, where m is the full mantissa shifted right by 14-exp
+		shift := uint(14 - exp)
+		if shift > 24 {
+			return F16{sign}
+		}
+		return F16{sign | uint16(roundShift(man|0x800000, shift))}
+	}
+	// a carry out of the mantissa correctly increments the exponent, up to infinity
+	return F16{sign | uint16(roundShift(uint32(exp)<<23|man, 13))}
+}
+
+// F16FromBits returns the F16 with the given bits.
+func F16FromBits(bits uint16) F16 { return F16{bits} }
+
+// Bits returns the bits of h.
+func (h F16) Bits() uint16 { return h.bits }
+
+// Float32 returns h as a float32, which it is exactly representable as.
+func (h F16) Float32() float32 {
+	sign := uint32(h.bits&0x8000) << 16
+	exp := uint32(h.bits>>10) & 0x1f
+	man := uint32(h.bits) & 0x3ff
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | man<<13)
+	case 0:
+		f := float32(man) / (1 << 24)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | man<<13)
+}
+
+func (h F16) String() string { return fmt.Sprint(h.Float32()) }
+
+// NewBF16 returns f rounded to the nearest BF16, with ties to even. NaNs stay NaNs.
+func NewBF16(f float32) BF16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 {
+		return BF16{uint16(b>>16) | 0x40}
+	}
+	return BF16{uint16(roundShift(b, 16))}
+}
+
+// BF16FromBits returns the BF16 with the given bits.
+func BF16FromBits(bits uint16) BF16 { return BF16{bits} }
+
+// Bits returns the bits of h.
+func (h BF16) Bits() uint16 { return h.bits }
+
+// Float32 returns h as a float32, which it is exactly representable as.
+func (h BF16) Float32() float32 { return math.Float32frombits(uint32(h.bits) << 16) }
+
+func (h BF16) String() string { return fmt.Sprint(h.Float32()) }
+
+// roundShift shifts x right by n bits, rounding to the nearest result, with ties to even.
+func roundShift(x uint32, n uint) uint32 {
+	retVal := x >> n
+	rem, half := x&(1<<n-1), uint32(1)<<(n-1)
+	if rem > half || rem == half && retVal&1 == 1 {
+		retVal++
+	}
+	return retVal
+}
+
+// floatOf returns v as a scalar of the float Dtype dt.
+func floatOf(v float32, dt tensor.Dtype) (interface{}, error) {
+	switch dt {
+	case tensor.Float32:
+		return v, nil
+	case tensor.Float64:
+		return float64(v), nil
+	case Float16:
+		return NewF16(v), nil
+	case BFloat16:
+		return NewBF16(v), nil
+	}
+	return nil, errors.Errorf("Expected a float Dtype. Got %v instead", dt)
+}
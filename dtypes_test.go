@@ -0,0 +1,471 @@
+package magol
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestF16(t *testing.T) {
+	cases := []struct {
+		f    float32
+		bits uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65519, 0x7bff},                      // just below the halfway point to infinity
+		{65520, 0x7c00},                      // halfway to infinity, which is even
+		{1e10, 0x7c00},                       // overflows
+		{float32(math.Inf(-1)), 0xfc00},      // stays infinite
+		{1 + 1.0/2048, 0x3c00},               // ties to even, down
+		{1 + 3.0/2048, 0x3c02},               // ties to even, up
+		{1.0 / (1 << 14), 0x0400},            // smallest normal
+		{1.0 / (1 << 24), 0x0001},            // smallest subnormal
+		{1.0 / (1 << 25), 0x0000},            // halfway to the smallest subnormal, ties to 0
+		{1.0 / (1 << 25) * 1.0001, 0x0001},   // just above halfway
+		{3.0 / (1 << 25), 0x0002},            // ties to even subnormals
+		{(1 - 1.0/2048) / (1 << 14), 0x0400}, // rounds up to the smallest normal
+		{float32(math.SmallestNonzeroFloat32), 0x0000},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.bits, NewF16(c.f).Bits(), "%v", c.f)
+	}
+
+	// every F16 survives a round trip through float32
+	for b := 0; b < 1<<16; b++ {
+		h := F16FromBits(uint16(b))
+		f := h.Float32()
+		if b&0x7c00 == 0x7c00 && b&0x3ff != 0 {
+			assert.True(t, f != f, "%#04x should be a NaN", b)
+			assert.True(t, NewF16(f).Float32() != NewF16(f).Float32(), "%#04x should stay a NaN", b)
+			continue
+		}
+		if NewF16(f) != h {
+			t.Errorf("%#04x became %#04x", b, NewF16(f).Bits())
+		}
+	}
+
+	// rounding is within half an ulp
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		f := (r.Float32()*2 - 1) * 60000
+		if math.Abs(float64(f)) < 1.0/(1<<14) {
+			continue
+		}
+		got := NewF16(f).Float32()
+		assert.InDelta(t, 0, (got-f)/f, 1.0/(1<<11), "%v became %v", f, got)
+	}
+}
+
+func TestBF16(t *testing.T) {
+	cases := []struct {
+		f    float32
+		bits uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3f80},
+		{-2, 0xc000},
+		{1 + 1.0/256, 0x3f80}, // ties to even, down
+		{1 + 3.0/256, 0x3f82}, // ties to even, up
+		{math.MaxFloat32, 0x7f80},
+		{float32(math.Inf(1)), 0x7f80},
+		{float32(math.SmallestNonzeroFloat32), 0x0000},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.bits, NewBF16(c.f).Bits(), "%v", c.f)
+	}
+	nan := NewBF16(math.Float32frombits(0x7f800001))
+	assert.True(t, nan.Float32() != nan.Float32(), "NaNs with low payloads should stay NaNs")
+
+	for b := 0; b < 1<<16; b++ {
+		h := BF16FromBits(uint16(b))
+		f := h.Float32()
+		if f != f {
+			continue
+		}
+		if NewBF16(f) != h {
+			t.Errorf("%#04x became %#04x", b, NewBF16(f).Bits())
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		f := (r.Float32()*2 - 1) * 1e30
+		got := NewBF16(f).Float32()
+		assert.InDelta(t, 0, (got-f)/f, 1.0/(1<<8), "%v became %v", f, got)
+	}
+}
+
+func TestHalfDtypes(t *testing.T) {
+	assert.Equal(t, uintptr(2), Float16.Size())
+	assert.Equal(t, uintptr(2), BFloat16.Size())
+	x := tensor.New(tensor.WithShape(2), tensor.WithBacking([]F16{NewF16(1), NewF16(2.5)}))
+	assert.Equal(t, Float16, x.Dtype())
+	v, err := x.At(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, NewF16(2.5), v)
+	assert.Equal(t, "2.5", v.(F16).String())
+}
+
+// halfOf returns the elements of data rounded to dt, back as float32s.
+func halfOf(data []float32, dt tensor.Dtype) []float32 {
+	retVal := make([]float32, len(data))
+	for i, f := range data {
+		v, _ := floatOf(f, dt)
+		switch h := v.(type) {
+		case F16:
+			retVal[i] = h.Float32()
+		case BF16:
+			retVal[i] = h.Float32()
+		default:
+			retVal[i] = h.(float32)
+		}
+	}
+	return retVal
+}
+
+// engineHalfTensor creates a tensor of the half precision dtype dt backed by engine memory, filled with data rounded to dt.
+func engineHalfTensor(t *testing.T, e *Engine, dt tensor.Dtype, data []float32, shape ...int) *tensor.Dense {
+	host := tensor.New(tensor.WithShape(shape...), tensor.Of(dt))
+	for i, f := range data {
+		v, err := floatOf(f, dt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		host.Set(i, v)
+	}
+	x := engineTensorOf(t, e, dt, shape...)
+	if err := e.Memcpy(x, host); err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+// engineTensorOf allocates an engine tensor of the given dtype and shape.
+func engineTensorOf(t *testing.T, e *Engine, dt tensor.Dtype, shape ...int) *tensor.Dense {
+	x, err := e.makeTensor(shape, dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x.(*tensor.Dense)
+}
+
+// readbackFloats reads the elements of an engine tensor of a float dtype back as float32s.
+func readbackFloats(t *testing.T, e *Engine, x tensor.Tensor) []float32 {
+	host := readback(t, e, x)
+	retVal := make([]float32, host.Shape().TotalSize())
+	for i := range retVal {
+		switch v := host.Get(i).(type) {
+		case F16:
+			retVal[i] = v.Float32()
+		case BF16:
+			retVal[i] = v.Float32()
+		default:
+			retVal[i] = v.(float32)
+		}
+	}
+	return retVal
+}
+
+func TestEngine_HalfOps(t *testing.T) {
+	aData, bData := makeRandom(4, 6), makeRandom(4, 6)
+	for _, dt := range []tensor.Dtype{Float16, BFloat16} {
+		t.Run(dt.String(), func(t *testing.T) {
+			// the results are computed in float32 and rounded, so they are within an ulp or so of the rounded float32 results
+			tol := float32(1.0 / (1 << 10))
+			if dt == BFloat16 {
+				tol = 1.0 / (1 << 7)
+			}
+			close := func(a, b float32) bool { return soclosef32(a, b, tol) }
+			a32, b32 := halfOf(aData, dt), halfOf(bData, dt)
+			std := func(fn func(a, b *tensor.Dense) (tensor.Tensor, error)) []float32 {
+				ret, err := fn(stdTensor(a32, 4, 6), stdTensor(b32, 4, 6))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return halfOf(floats(ret.Data()), dt)
+			}
+
+			e := newTestEngine(t)
+			a, b := engineHalfTensor(t, e, dt, aData, 4, 6), engineHalfTensor(t, e, dt, bData, 4, 6)
+			sum, err := e.Add(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, dt, sum.Dtype())
+			assert.True(t, allWithinRange(std(func(a, b *tensor.Dense) (tensor.Tensor, error) { return tensor.Add(a, b) }), readbackFloats(t, e, sum), close), "Add")
+
+			half, _ := floatOf(0.5, dt)
+			scaled, err := e.MulScalar(a, half, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, allWithinRange(std(func(a, _ *tensor.Dense) (tensor.Tensor, error) { return tensor.Mul(a, float32(0.5)) }), readbackFloats(t, e, scaled), close), "MulScalar")
+
+			exp, err := e.Exp(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, allWithinRange(std(func(a, _ *tensor.Dense) (tensor.Tensor, error) { return tensor.Exp(a) }), readbackFloats(t, e, exp), close), "Exp")
+
+			mean, err := e.Mean(a, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, allWithinRange(std(func(a, _ *tensor.Dense) (tensor.Tensor, error) {
+				sum, err := tensor.Sum(a, 1)
+				if err != nil {
+					return nil, err
+				}
+				return tensor.Div(sum, float32(6))
+			}), readbackFloats(t, e, mean), close), "Mean")
+
+			sm, err := e.SoftMax(a, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the softmax of BFloat16s is composed of ops that each round their result
+			loose := func(a, b float32) bool { return soclosef32(a, b, 4*tol) }
+			assert.True(t, allWithinRange(std(func(a, _ *tensor.Dense) (tensor.Tensor, error) { return tensor.SoftMax(a, 1) }), readbackFloats(t, e, sm), loose), "SoftMax")
+
+			am, err := e.Argmax(a, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := stdTensor(a32, 4, 6).Argmax(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want.Data(), readback(t, e, am).Data(), "Argmax")
+
+			_, err = e.Add(a, engineTensor(t, e, aData, 4, 6))
+			assert.Error(t, err, "mixing dtypes")
+		})
+	}
+}
+
+func TestEngine_HalfMatMul(t *testing.T) {
+	const m, k, n = 3, 4, 5
+	aData, bData := makeRandom(m, k), makeRandom(k, n)
+
+	e := newTestEngine(t)
+	a, b := engineHalfTensor(t, e, Float16, aData, m, k), engineHalfTensor(t, e, Float16, bData, k, n)
+	c := engineTensorOf(t, e, Float16, m, n)
+	if err := e.MatMul(a, b, c); err != nil {
+		t.Fatal(err)
+	}
+	want := halfOf(naiveBatchedMatMul(halfOf(aData, Float16), halfOf(bData, Float16), 1, m, k, n, false), Float16)
+	assert.True(t, allWithinRange(want, readbackFloats(t, e, c), func(a, b float32) bool { return soclosef32(a, b, 1.0/(1<<10)) }))
+
+	// MPS has no bfloat16 matrices
+	abf, bbf := engineHalfTensor(t, e, BFloat16, aData, m, k), engineHalfTensor(t, e, BFloat16, bData, k, n)
+	assert.Error(t, e.MatMul(abf, bbf, engineTensorOf(t, e, BFloat16, m, n)))
+	// nor mixed dtypes
+	assert.Error(t, e.MatMul(a, b, engineTensor(t, e, make([]float32, m*n), m, n)))
+}
+
+// intsOf returns vs converted to a slice of the int dtype dt, wrapping around like kernels do.
+func intsOf(vs []int64, dt tensor.Dtype) interface{} {
+	switch dt {
+	case tensor.Int8:
+		retVal := make([]int8, len(vs))
+		for i, v := range vs {
+			retVal[i] = int8(v)
+		}
+		return retVal
+	case tensor.Uint8:
+		retVal := make([]uint8, len(vs))
+		for i, v := range vs {
+			retVal[i] = uint8(v)
+		}
+		return retVal
+	}
+	retVal := make([]int32, len(vs))
+	for i, v := range vs {
+		retVal[i] = int32(v)
+	}
+	return retVal
+}
+
+// engineCopy copies the host tensor x into a new engine tensor.
+func engineCopy(t *testing.T, e *Engine, x *tensor.Dense) *tensor.Dense {
+	retVal := engineTensorOf(t, e, x.Dtype(), x.Shape()...)
+	if err := e.Memcpy(retVal, x); err != nil {
+		t.Fatal(err)
+	}
+	return retVal
+}
+
+func TestEngine_IntOps(t *testing.T) {
+	aData := []int64{7, 100, 3, 0, 12, 9}
+	bData := []int64{2, 100, 3, 4, 0, 5}
+	for _, dt := range intDtypes {
+		t.Run(dt.String(), func(t *testing.T) {
+			e := newTestEngine(t)
+			a := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(intsOf(aData, dt))))
+			b := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(intsOf(bData, dt))))
+			binCases := []struct {
+				name string
+				op   func(a, b tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+				ref  func(a, b int64) int64
+			}{
+				{"Add", e.Add, hostIntBinOps["add"]}, // 100 + 100 wraps around for Int8 and Uint8
+				{"Sub", e.Sub, hostIntBinOps["sub"]},
+				{"Mul", e.Mul, hostIntBinOps["mul"]},
+				{"Div", e.Div, hostIntBinOps["div"]}, // 12 / 0 is 0
+				{"Mod", e.Mod, hostIntBinOps["mod"]},
+				{"MaxBetween", e.MaxBetween, hostIntBinOps["max"]},
+			}
+			for _, c := range binCases {
+				want := make([]int64, len(aData))
+				for i := range want {
+					want[i] = c.ref(aData[i], bData[i])
+				}
+				ret, err := c.op(a, b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, dt, ret.Dtype())
+				assert.Equal(t, intsOf(want, dt), readback(t, e, ret).Data(), c.name)
+			}
+
+			// the first row of a is broadcast
+			row, err := a.Slice(tensor.S(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum, err := e.Add(b, row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, intsOf([]int64{9, 200, 6, 11, 100, 8}, dt), readback(t, e, sum).Data(), "broadcast Add")
+
+			three := reflect.ValueOf(intsOf([]int64{3}, dt)).Index(0).Interface()
+			scaled, err := e.MulScalar(a, three, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, intsOf([]int64{21, 300, 9, 0, 36, 27}, dt), readback(t, e, scaled).Data(), "MulScalar")
+
+			neg, err := e.Neg(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, intsOf([]int64{-7, -100, -3, 0, -12, -9}, dt), readback(t, e, neg).Data(), "Neg")
+			sign, err := e.Sign(neg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantSign := []int64{-1, -1, -1, 0, -1, -1}
+			if dt == tensor.Uint8 {
+				wantSign = []int64{1, 1, 1, 0, 1, 1}
+			}
+			assert.Equal(t, intsOf(wantSign, dt), readback(t, e, sign).Data(), "Sign")
+
+			// transposed views are copied
+			if err = a.T(); err != nil {
+				t.Fatal(err)
+			}
+			m, err := e.Materialize(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, intsOf([]int64{7, 0, 100, 12, 3, 9}, dt), readback(t, e, m).Data(), "Materialize")
+
+			_, err = e.Exp(b)
+			assert.Error(t, err, "ints have no transcendentals")
+			_, err = e.Sum(b)
+			assert.Error(t, err, "nor reductions")
+		})
+	}
+}
+
+func TestEngine_Bools(t *testing.T) {
+	e := newTestEngine(t)
+	mask := engineCopy(t, e, tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]bool{true, false, true, true})))
+	if err := mask.T(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := e.Materialize(mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []bool{true, true, false, true}, readback(t, e, m).Data())
+
+	_, err = e.Add(m, m)
+	assert.Error(t, err, "Bools have no arithmetic")
+}
+
+// TestEngine_UnsupportedOps checks that ops with no kernels for the Dtype of their operands fail before allocating anything.
+func TestEngine_UnsupportedOps(t *testing.T) {
+	e := newTestEngine(t)
+	i := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]int32{1, 2, 3, 4, 5, 6})))
+	mask := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]bool{true, false, true, false, true, false})))
+	ops := []struct {
+		name string
+		op   func() error
+	}{
+		{"Sum", func() error { _, err := e.Sum(i); return err }},
+		{"Mean", func() error { _, err := e.Mean(i, 0); return err }},
+		{"Exp", func() error { _, err := e.Exp(i); return err }},
+		{"Argmax", func() error { _, err := e.Argmax(i, 0); return err }},
+		{"Pow", func() error { _, err := e.Pow(i, i); return err }},
+		{"SoftMax", func() error { _, err := e.SoftMax(i, 0); return err }},
+		{"SoftMaxB", func() error { _, err := e.SoftMaxB(i, i, 0); return err }},
+		{"Dot", func() error { _, err := e.Dot(i, i); return err }},
+		{"Add Bools", func() error { _, err := e.Add(mask, mask); return err }},
+		{"Gt Bools", func() error { _, err := e.Gt(mask, mask); return err }},
+		{"Clamp Bools", func() error { _, err := e.Clamp(mask, false, true); return err }},
+	}
+	live := liveAllocs(e)
+	for _, op := range ops {
+		assert.Error(t, op.op(), op.name)
+	}
+	assert.Equal(t, live, liveAllocs(e), "nothing should have been allocated")
+
+	_, err := e.Sum(i)
+	assert.EqualError(t, err, "Sum(): Unsupported Dtype int32: there is no kernel reduce_sum_i32")
+}
+
+func TestCmpKernels(t *testing.T) {
+	e := newTestEngine(t)
+	run := func(kernel string, result tensor.Dtype, consts [][]byte, args ...*tensor.Dense) interface{} {
+		ret := engineTensorOf(t, e, result, args[0].Shape()...)
+		var ts []tensor.Memory
+		for _, a := range args {
+			ts = append(ts, a)
+		}
+		if err := e.dispatch(kernel, ret.Shape().TotalSize(), consts, append(ts, ret)...); err != nil {
+			t.Fatal(err)
+		}
+		return readback(t, e, ret).Data()
+	}
+
+	a := engineTensor(t, e, []float32{1, 2, 3, float32(math.NaN())}, 4)
+	b := engineTensor(t, e, []float32{3, 2, 1, 1}, 4)
+	assert.Equal(t, []bool{false, false, true, false}, run("gt_bool_f32", tensor.Bool, nil, a, b))
+	assert.Equal(t, []bool{true, false, true, true}, run("ne_bool_f32", tensor.Bool, nil, a, b))
+	assert.Equal(t, []float32{1, 1, 0, 0}, run("lte_f32_f32", tensor.Float32, nil, a, b))
+	assert.Equal(t, []float32{0, 1, 1, 0}, run("gte_vs_f32_f32", tensor.Float32, [][]byte{bytesOf(float32(2))}, a))
+
+	h := engineHalfTensor(t, e, Float16, []float32{1, 2, 3, 4}, 4)
+	assert.Equal(t, []bool{false, false, true, true}, run("lt_sv_bool_f16", tensor.Bool, [][]byte{bytesOf(NewF16(2))}, h))
+
+	i := engineCopy(t, e, tensor.New(tensor.WithBacking([]int8{-1, 0, 1, 2})))
+	j := engineCopy(t, e, tensor.New(tensor.WithBacking([]int8{-1, 1, 1, -2})))
+	assert.Equal(t, []bool{true, false, true, false}, run("eq_bool_i8", tensor.Bool, nil, i, j))
+	assert.Equal(t, []int8{0, 1, 0, 0}, run("lt_i8_i8", tensor.Int8, nil, i, j))
+
+	p := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, false, true, false})))
+	q := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, true, false, false})))
+	assert.Equal(t, []bool{true, false, false, true}, run("eq_bool_bool", tensor.Bool, nil, p, q))
+}
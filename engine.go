@@ -1,280 +1,361 @@
 package magol
 
-/*
-#cgo LDFLAGS: -framework Metal -framework CoreGraphics -framework Foundation -framework MetalPerformanceShaders
-#include <stdlib.h>
-#include <stdbool.h>
-#include <stdio.h>
-#include "magol.h"
-*/
-import "C"
 import (
-	"unsafe"
+	"context"
 
 	"github.com/pkg/errors"
 	"gorgonia.org/tensor"
 )
 
 var (
-	_ tensor.Adder    = &Engine{}
-	_ tensor.MatMuler = &Engine{}
+	_ tensor.Adder        = &Engine{}
+	_ tensor.Suber        = &Engine{}
+	_ tensor.Muler        = &Engine{}
+	_ tensor.Diver        = &Engine{}
+	_ tensor.Power        = &Engine{}
+	_ tensor.Moder        = &Engine{}
+	_ tensor.MinBetweener = &Engine{}
+	_ tensor.MaxBetweener = &Engine{}
+	_ tensor.Exper        = &Engine{}
+	_ tensor.Loger        = &Engine{}
+	_ tensor.Sqrter       = &Engine{}
+	_ tensor.InvSqrter    = &Engine{}
+	_ tensor.Tanher       = &Engine{}
+	_ tensor.Abser        = &Engine{}
+	_ tensor.Neger        = &Engine{}
+	_ tensor.Signer       = &Engine{}
+	_ tensor.Squarer      = &Engine{}
+	_ tensor.Cuber        = &Engine{}
+	_ tensor.Sumer        = &Engine{}
+	_ tensor.Proder       = &Engine{}
+	_ tensor.Maxer        = &Engine{}
+	_ tensor.Miner        = &Engine{}
+	_ tensor.Argmaxer     = &Engine{}
+	_ tensor.Argminer     = &Engine{}
+	_ tensor.Gter         = &Engine{}
+	_ tensor.Gteer        = &Engine{}
+	_ tensor.Lter         = &Engine{}
+	_ tensor.Lteer        = &Engine{}
+	_ tensor.ElEqer       = &Engine{}
+	_ tensor.Clamper      = &Engine{}
+	_ tensor.Transposer   = &Engine{}
+	_ tensor.MatMuler     = &Engine{}
+	_ tensor.MatVecMuler  = &Engine{}
+	_ tensor.OuterProder  = &Engine{}
+	_ tensor.InnerProder  = &Engine{}
+	_ tensor.Dotter       = &Engine{}
 )
 
-const library = `
-kernel void add(device const float* inA,
-                       device const float* inB,
-                       device float* result,
-                       uint index [[thread_position_in_grid]])
-{
-    result[index] = inA[index] + inB[index];
-}
+// DefaultBatchSize is the default number of ops an Engine encodes before committing them to the device.
+const DefaultBatchSize = 64
 
-kernel void addScalar(device const float* inVec,
-                      device const float* inScalar,
-                      device float* result,
-                      uint index [[thread_position_in_grid]]) {
-    result[index] = inVec[index] + *inScalar;
-}
+// Engine is a tensor.Engine that executes on a Backend.
+//
+// Ops are batched: they are encoded into a pending batch of work which is committed when the host accesses
+// device memory through the Engine (Memcpy, Memset, Accessible, ...), when Sync is called,
+// or when the number of pending ops reaches the batch size.
+type Engine struct {
+	b     Backend
+	alloc *CachingAllocator
+	reg   *registry
+	q     *batcher
+
+	ctx context.Context // nil unless the Engine was made by WithContext
 
-kernel void sub(device const float* inA,
-                       device const float* inB,
-                       device float* result,
-                       uint index [[thread_position_in_grid]])
-{
-    result[index] = inA[index] - inB[index];
+	cacheLimit    int64
+	batchSize     int
+	indexDtype    tensor.Dtype
+	float64OnHost bool
 }
 
-kernel void mul(device const float* inA,
-                       device const float* inB,
-                       device float* result,
-                       uint index [[thread_position_in_grid]])
-{
-    result[index] = inA[index] * inB[index];
+// EngineOpt is an option for creating an Engine.
+type EngineOpt func(*Engine)
+
+// WithCacheLimit sets the maximum number of bytes of freed memory the Engine caches for reuse.
+// A limit of 0 disables caching.
+func WithCacheLimit(bytes int64) EngineOpt {
+	return func(e *Engine) { e.cacheLimit = bytes }
 }
-`
 
-type byteslice []byte
+// WithBatchSize sets the number of ops the Engine encodes before committing them to the device.
+// A batch size of 1 commits every op as soon as it is encoded.
+func WithBatchSize(n int) EngineOpt {
+	return func(e *Engine) { e.batchSize = n }
+}
 
-func (b byteslice) Uintptr() uintptr { return uintptr(unsafe.Pointer(&b[0])) }
-func (b byteslice) MemSize() uintptr { return uintptr(len(b)) }
+// WithIndexDtype sets the dtype of the indices returned by Argmax and Argmin: tensor.Int, the default, or tensor.Int32.
+func WithIndexDtype(dt tensor.Dtype) EngineOpt {
+	return func(e *Engine) { e.indexDtype = dt }
+}
 
-type Engine struct {
-	d *Device
-	q CommandQueue
-	l Library
+// WithFloat64OnHost makes the Engine run its ops on Float64 tensors, which there are no kernels for, with tensor.StdEng on the host.
+// Without it, they fail. See float64.go.
+func WithFloat64OnHost() EngineOpt {
+	return func(e *Engine) { e.float64OnHost = true }
+}
 
-	fns map[string]Function
+// NewEngineWithBackend creates an Engine that executes on the given Backend.
+func NewEngineWithBackend(b Backend, opts ...EngineOpt) *Engine {
+	e := &Engine{
+		b:          b,
+		reg:        new(registry),
+		q:          new(batcher),
+		cacheLimit: DefaultCacheLimit,
+		batchSize:  DefaultBatchSize,
+		indexDtype: tensor.Int,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.alloc = NewCachingAllocator(b, e.cacheLimit)
+	return e
 }
 
-func NewEngine(d *Device) *Engine {
-	l, err := d.MakeLibrary(library)
+func (e *Engine) AllocAccessible() bool { return true }
+func (e *Engine) Alloc(size int64) (tensor.Memory, error) {
+	buf, err := e.alloc.Alloc(size)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if err = e.reg.insert(buf); err != nil {
+		e.alloc.Free(buf)
+		return nil, err
 	}
-	add, err := l.MakeFunction("add")
+	return buf, nil
+}
+func (e *Engine) Free(mem tensor.Memory, size int64) error {
+	mBuf, err := e.reg.remove(mem.Uintptr())
 	if err != nil {
-		panic(err)
+		return errors.Wrap(err, "Free()")
 	}
-	return &Engine{
-		d: d,
-		q: MakeCommandQueue(d),
+	return e.alloc.Free(mBuf)
+}
+
+// EmptyCache releases all cached memory back to the Backend.
+func (e *Engine) EmptyCache() error { return e.alloc.EmptyCache() }
+
+// AllocatorStats returns the hit/miss counters of the Engine's allocator.
+func (e *Engine) AllocatorStats() AllocatorStats { return e.alloc.Stats() }
+
+// WorksWith reports whether the Engine works with tensors of the given data order directly.
+// Kernels index their operands through their strides, so row and column major tensors, transposed or not,
+// contiguous or not, are all worked with directly.
+func (e *Engine) WorksWith(order tensor.DataOrder) bool {
+	return order&^(tensor.ColMajor|tensor.NonContiguous|tensor.Transposed) == 0
+}
 
-		fns: map[string]Function{"add": add},
+func (e *Engine) MatMul(a, b, prealloc tensor.Tensor) error {
+	if ok, err := e.onHost(func(std tensor.StdEng) error { return std.MatMul(a, b, prealloc) }, a, b, prealloc); ok {
+		return errors.Wrap(err, "MatMul()")
+	}
+	if err := e.gemm(false, false, 1, a, b, 0, prealloc); err != nil {
+		return errors.Wrap(err, "MatMul()")
 	}
+	return nil
 }
 
-func (e *Engine) AllocAccessible() bool                   { return true }
-func (e *Engine) Alloc(size int64) (tensor.Memory, error) { return AllocMBuf(e.d, size), nil } // TODO handle errors
-func (e *Engine) Free(mem tensor.Memory, size int64) error {
-	mBuf, ok := mem.(Buffer)
-	if !ok {
-		return errors.Errorf("Expected a Buffer. Got a Memory of %T instead", mem)
+// Gemm computes C = alpha·op(A)·op(B) + beta·C, where op transposes its input if the corresponding flag is set.
+// With a beta of 1, the product is accumulated into C, as in C += Aᵀ·dY when backpropagating.
+//
+// Neither the flags nor transposed views make A or B be copied.
+func (e *Engine) Gemm(transA, transB bool, alpha float32, A, B tensor.Tensor, beta float32, C tensor.Tensor) error {
+	if err := e.gemm(transA, transB, alpha, A, B, beta, C); err != nil {
+		return errors.Wrap(err, "Gemm()")
 	}
-	mBuf.Free()
 	return nil
 }
-func (e *Engine) Memset(mem tensor.Memory, val interface{}) error     { panic("NYI") }
-func (e *Engine) Memclr(mem tensor.Memory)                            { panic("NYI") }
-func (e *Engine) Memcpy(dst, src tensor.Memory) error                 { panic("NYI") }
-func (e *Engine) Accessible(mem tensor.Memory) (tensor.Memory, error) { panic("NYI") }
-func (e *Engine) WorksWith(order tensor.DataOrder) bool               { return true } // for now
-
-func (e *Engine) Add(a, b tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	reuse, safe, _, _, err := e.handleFuncOpts(a.Shape(), a.Dtype(), a.DataOrder(), opts...)
+
+func (e *Engine) gemm(transA, transB bool, alpha float32, a, b tensor.Tensor, beta float32, c tensor.Tensor) error {
+	ad, bd, retVal, err := e.checkValidMatmulInput(a, b, c, transA, transB)
 	if err != nil {
-		return nil, errors.Wrap(err, "Add()")
+		return err
 	}
-	cmdBuf := e.q.CommandBuffer()
-	pso, err := e.d.MakeComputePipeline(e.fns["add"])
+
+	ad, al, tA, err := e.matrixOperand(ad, true)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	elements := a.Shape().TotalSize()
-	switch {
-	case safe && reuse == nil:
-		// make reuse
-		reuseMem, err := e.Alloc(int64(elements * 4))
-		if err != nil {
-			return nil, errors.Wrapf(err, "Unable to allocate %d float32s for the result", elements)
+	if ad != a {
+		defer e.release(ad)
+	}
+	bd, bl, tB, err := e.matrixOperand(bd, true)
+	if err != nil {
+		return err
+	}
+	if bd != b {
+		defer e.release(bd)
+	}
+	cd, cl, transC, err := e.matrixResult(retVal, true)
+	if err != nil {
+		return err
+	}
+	if cd != retVal && beta != 0 {
+		// the temporary result is accumulated into
+		if err = e.copyInto(cd, retVal); err != nil {
+			e.discardResult(retVal, cd)
+			return err
 		}
-		reuse = tensor.New(tensor.WithShape(a.Shape().Clone()...), tensor.Of(a.Dtype()), tensor.WithEngine(e), tensor.FromMemory(reuseMem.Uintptr(), reuseMem.MemSize()))
-		fallthrough
-	case safe && reuse != nil:
-		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(a).b, memAsMBuf(b).b, memAsMBuf(reuse).b, C.size_t(elements))
-		retVal = reuse
-		return
-	case !safe:
-		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(a).b, memAsMBuf(b).b, memAsMBuf(a).b, C.size_t(elements))
-		retVal = a
-		return
-	}
-	panic("Unreachable")
+	}
+
+	bufs, err := e.buffersOf(ad, bd, cd)
+	if err != nil {
+		e.discardResult(retVal, cd)
+		return err
+	}
+	// a transposed view of a transposed operand is the operand itself
+	if err = e.encodeMatMul(bufs, al, bl, cl, tA != transA, tB != transB, transC, alpha, beta); err != nil {
+		e.discardResult(retVal, cd)
+		return err
+	}
+	return e.finishResult(retVal, cd)
 }
 
-func (e *Engine) AddScalar(a tensor.Tensor, b interface{}, leftTensor bool, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	// reuse, safe, _, _, err := e.handleFuncOpts(a.Shape(), a.Dtype(), a.DataOrder(), opts...)
-	// if err != nil {
-	// 	return nil, errors.Wrap(err, "AddScalar()")
-	// }
-	// cmdBuf := e.q.CommandBuffer()
-	// pso, err := e.d.MakeComputePipeline(e.fns["addScalar"])
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// elements := a.Shape().TotalSize()
-	// switch {
-	// case safe && reuse == nil:
-	// 	// make reuse
-	// 	reuseMem, err := e.Alloc(int64(elements * 4))
-	// 	if err != nil {
-	// 		return nil, errors.Wrapf(err, "Unable to allocate %d float32s for the result", elements)
-	// 	}
-	// 	reuse = tensor.New(tensor.WithShape(a.Shape().Clone()...), tensor.Of(a.Dtype()), tensor.WithEngine(e), tensor.FromMemory(reuseMem.Uintptr(), reuseMem.MemSize()))
-	// 	fallthrough
-	// case safe && reuse != nil:
-	// 	if leftTensor {
-	// 		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(a).b, memAsMBuf(b).b, memAsMBuf(reuse).b, C.size_t(elements))
-	// 	} else {
-	// 		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(b).b, memAsMBuf(a).b, memAsMBuf(reuse).b, C.size_t(elements))
-	// 	}
-	// 	retVal = reuse
-	// 	return
-	// case !safe:
-	// 	if leftTensor {
-	// 		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(a).b, memAsMBuf(b).b, memAsMBuf(a).b, C.size_t(elements))
-	// 	} else {
-	// 		C.RunBinFunc(cmdBuf.b, pso.p, memAsMBuf(b).b, memAsMBuf(a).b, memAsMBuf(a).b, C.size_t(elements))
-	// 	}
-	// 	retVal = a
-	// 	return
-	// }
-	panic("Unreachable")
+// encodeMatMul encodes C = alpha·op(A)·op(B) + beta·C on the buffers of A, B and C.
+func (e *Engine) encodeMatMul(bufs []Buffer, al, bl, cl MatrixLayout, transA, transB, transC bool, alpha, beta float32) error {
+	return e.encode(func() error {
+		if transC {
+			// MPS can't write a transposed result, so compute Cᵀ = alpha·op(B)ᵀ·op(A)ᵀ + beta·Cᵀ instead
+			return e.b.MatMul(bufs[1], bufs[0], bufs[2], bl, al, cl, !transB, !transA, alpha, beta)
+		}
+		return e.b.MatMul(bufs[0], bufs[1], bufs[2], al, bl, cl, transA, transB, alpha, beta)
+	})
 }
 
-func (e *Engine) MatMul(a, b, prealloc tensor.Tensor) error {
-	// select {
-	// case <-ctx.Done():
-	// 	return errors.New("NoOp")
-	// default:
-	// }
-	ad, bd, retVal, err := e.checkValidMatmulInput(a, b, prealloc)
+func (e *Engine) MatVecMul(a, b, prealloc tensor.Tensor) error {
+	if ok, err := e.onHost(func(std tensor.StdEng) error { return std.MatVecMul(a, b, prealloc) }, a, b, prealloc); ok {
+		return errors.Wrap(err, "MatVecMul()")
+	}
+	if err := e.gemv(false, a, b, prealloc); err != nil {
+		return errors.Wrap(err, "MatVecMul()")
+	}
+	return nil
+}
+
+// gemv computes y = op(A)·x, where op transposes A if transA is set.
+func (e *Engine) gemv(transA bool, a, x, y tensor.Tensor) error {
+	ad, xd, retVal, err := e.checkValidMatVecMulInput(a, x, y, transA)
+	if err != nil {
+		return err
+	}
+	ad, al, tA, err := e.matrixOperand(ad, true)
+	if err != nil {
+		return err
+	}
+	if ad != a {
+		defer e.release(ad)
+	}
+	xd, xl, err := e.vectorOperand(xd)
+	if err != nil {
+		return err
+	}
+	if xd != x {
+		defer e.release(xd)
+	}
+	yd, yl, err := e.vectorResult(retVal)
 	if err != nil {
 		return err
 	}
 
-	aDesc := pls(desc2MDesc(ad))
-	bDesc := pls(desc2MDesc(bd))
-	cDesc := pls(desc2MDesc(retVal))
-
-	aBuf := memAsMBuf(ad)
-	bBuf := memAsMBuf(bd)
-	cBuf := memAsMBuf(retVal)
+	bufs, err := e.buffersOf(ad, xd, yd)
+	if err != nil {
+		e.discardResult(retVal, yd)
+		return err
+	}
+	err = e.encode(func() error {
+		return e.b.MatVecMul(bufs[0], bufs[1], bufs[2], al, xl, yl, tA != transA)
+	})
+	if err != nil {
+		e.discardResult(retVal, yd)
+		return err
+	}
+	return e.finishResult(retVal, yd)
+}
 
-	A, B, C := NewMatrix(aBuf, aDesc), NewMatrix(bBuf, bDesc), NewMatrix(cBuf, cDesc)
+// makeTensor allocates a tensor of the given shape and dtype on the Engine.
+func (e *Engine) makeTensor(shape tensor.Shape, dt tensor.Dtype) (tensor.DenseTensor, error) {
+	elements := shape.TotalSize()
+	mem, err := e.Alloc(int64(elements) * int64(dt.Size()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to allocate %d %vs for the result", elements, dt)
+	}
+	return tensor.New(tensor.WithShape(shape.Clone()...), tensor.Of(dt), tensor.WithEngine(e), tensor.FromMemory(mem.Uintptr(), mem.MemSize())), nil
+}
 
-	cmdBuf := e.q.CommandBuffer()
-	return MPSMatMul(cmdBuf, A, B, C)
+// buffersOf returns the Buffers backing each of mems.
+func (e *Engine) buffersOf(mems ...tensor.Memory) ([]Buffer, error) {
+	bufs := make([]Buffer, len(mems))
+	for i, mem := range mems {
+		buf, err := e.bufferOf(mem)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Input %d", i)
+		}
+		bufs[i] = buf
+	}
+	return bufs, nil
 }
 
-func (e *Engine) MatVecMul(a, b, prealloc tensor.Tensor) error {
-	// select {
-	// case <-ctx.Done():
-	// 	return errors.New("NoOp")
-	// default:
-	// }
-	ad, bd, retVal, err := e.checkValidMatVecMulInput(a, b, prealloc)
+// dispatch runs the named kernel over n elements of the given tensors, passing consts as constant arguments.
+func (e *Engine) dispatch(kernel string, n int, consts [][]byte, ts ...tensor.Memory) error {
+	bufs, err := e.buffersOf(ts...)
 	if err != nil {
-		return nil
+		return err
 	}
-	aDesc := pls(desc2MDesc(ad))
-	bDesc := pls(desc2VDesc(bd))
-	cDesc := pls(desc2VDesc(retVal))
-
-	aBuf := memAsMBuf(ad)
-	bBuf := memAsMBuf(bd)
-	cBuf := memAsMBuf(retVal)
+	return e.encode(func() error {
+		return e.b.Dispatch(kernel, n, consts, bufs...)
+	})
+}
 
-	A, v, c := NewMatrix(aBuf, aDesc), NewVector(bBuf, bDesc), NewVector(cBuf, cDesc)
-	cmdBuf := e.q.CommandBuffer()
-	return MPSMatVecMul(cmdBuf, A, v, c)
+// dispatchGroups runs the named reduction kernel over groups threadgroups, passing consts as constant arguments.
+func (e *Engine) dispatchGroups(kernel string, groups int, consts [][]byte, ts ...tensor.Memory) error {
+	bufs, err := e.buffersOf(ts...)
+	if err != nil {
+		return err
+	}
+	return e.encode(func() error {
+		return e.b.DispatchGroups(kernel, groups, consts, bufs...)
+	})
 }
 
+// checkValidDtype checks that the tensors are all of the same Dtype, which kernels are generated for.
+// Not every op has kernels for every such Dtype, so ops also look their kernels up with checkKernel before allocating their results.
 func (e *Engine) checkValidDtype(ts ...tensor.Tensor) error {
 	for i, t := range ts {
-		if t.Dtype() != tensor.Float32 {
-			return errors.Errorf("Expected all inputs to be Float32s. Input %d is of type %v", i, t.Dtype())
+		if !hasKernels(t.Dtype()) {
+			return errors.Errorf("Unsupported Dtype %v of input %d", t.Dtype(), i)
+		}
+		if t.Dtype() != ts[0].Dtype() {
+			return errors.Errorf("Expected all inputs to be of the same Dtype. Input %d is of type %v, and input 0 of type %v", i, t.Dtype(), ts[0].Dtype())
 		}
 	}
 	return nil
 }
 
-func (e *Engine) LogSoftMax(x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	panic("NYI")
-}
-func (e *Engine) LogSoftMaxB(output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	panic("NYI")
-}
-func (e *Engine) SoftMax(x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	reuse, safe, _, _, err := e.handleFuncOpts(x.Shape(), x.Dtype(), x.DataOrder(), opts...)
-	if err != nil {
-		return nil, err
+// checkKernel checks that the library has the named kernels, which an op on tensors of the Dtype dt runs.
+func (e *Engine) checkKernel(dt tensor.Dtype, kernels ...string) error {
+	for _, kernel := range kernels {
+		if !kernelNames[kernel] {
+			return errors.Errorf("Unsupported Dtype %v: there is no kernel %v", dt, kernel)
+		}
 	}
+	return nil
+}
 
-	elements := x.Shape().TotalSize()
-	switch {
-	case safe && reuse == nil:
-		// then we need to make reuse
-		reuseMem, err := e.Alloc(int64(elements * 4))
-		if err != nil {
-			return nil, errors.Wrapf(err, "Unable to allocate %d float32s for the resulting tensor", elements)
+// checkLinalgDtype checks that the tensors are all of the same Dtype, which the matrix ops of the Backend support.
+func (e *Engine) checkLinalgDtype(ts ...tensor.Tensor) error {
+	for i, t := range ts {
+		if !isLinalgDtype(t.Dtype()) {
+			return errors.Errorf("Matrix ops are not supported for Dtype %v of input %d", t.Dtype(), i)
 		}
-		reuse = tensor.New(tensor.WithShape(x.Shape().Clone()...), tensor.Of(x.Dtype()), tensor.WithEngine(e), tensor.FromMemory(reuseMem.Uintptr(), reuseMem.MemSize()))
-		fallthrough
-	case safe && reuse != nil:
-		// we can just reuse reuse
-		xd := x.(tensor.DenseTensor)
-		xDesc := pls(desc2MDesc(xd))
-		rDesc := pls(desc2MDesc(reuse))
-		xBuf := memAsMBuf(xd)
-		rBuf := memAsMBuf(reuse)
-		A, Out := NewMatrix(xBuf, xDesc), NewMatrix(rBuf, rDesc)
-
-		cmdBuf := e.q.CommandBuffer()
-		err = MPSSoftmax(cmdBuf, A, Out)
-		return reuse, err
-	case !safe:
-		// then A is the result as well as input
-
-	}
-	panic("Unreachable")
-}
-func (e *Engine) SoftMaxB(output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
-	panic("NYI")
+		if t.Dtype() != ts[0].Dtype() {
+			return errors.Errorf("Expected all inputs to be of the same Dtype. Input %d is of type %v, and input 0 of type %v", i, t.Dtype(), ts[0].Dtype())
+		}
+	}
+	return nil
 }
 
-func (e *Engine) checkValidMatmulInput(a, b, ret tensor.Tensor) (ad, bd, retVal tensor.DenseTensor, err error) {
-	if a.Dtype() != tensor.Float32 || a.Dtype() != b.Dtype() || b.Dtype() != ret.Dtype() {
-		return nil, nil, nil, errors.New("Expected a and b and retVal all to have the same Dtype")
+func (e *Engine) checkValidMatmulInput(a, b, ret tensor.Tensor, transA, transB bool) (ad, bd, retVal tensor.DenseTensor, err error) {
+	if err = e.checkLinalgDtype(a, b, ret); err != nil {
+		return nil, nil, nil, err
 	}
 	ad, ok := a.(tensor.DenseTensor)
 	if !ok {
@@ -291,16 +372,27 @@ func (e *Engine) checkValidMatmulInput(a, b, ret tensor.Tensor) (ad, bd, retVal
 	if ad.Shape().Dims() != 2 || bd.Shape().Dims() != 2 || retVal.Shape().Dims() != 2 {
 		return nil, nil, nil, errors.New("Expected a, b, and retVal to be 2D")
 	}
-	if ad.Shape()[1] != bd.Shape()[0] {
+	m, k := ad.Shape()[0], ad.Shape()[1]
+	if transA {
+		m, k = k, m
+	}
+	k2, n := bd.Shape()[0], bd.Shape()[1]
+	if transB {
+		k2, n = n, k2
+	}
+	if k != k2 {
 		return nil, nil, nil, errors.New("Expected the inner dimensions of a and b to match")
 	}
+	if !retVal.Shape().Eq(tensor.Shape{m, n}) {
+		return nil, nil, nil, errors.Errorf("Expected retVal to be of shape %v. Got %v instead", tensor.Shape{m, n}, retVal.Shape())
+	}
 
 	return ad, bd, retVal, nil
 }
 
-func (e *Engine) checkValidMatVecMulInput(a, b, ret tensor.Tensor) (ad, bd, retVal tensor.DenseTensor, err error) {
-	if a.Dtype() != tensor.Float32 || a.Dtype() != b.Dtype() || b.Dtype() != ret.Dtype() {
-		return nil, nil, nil, errors.New("Expected a and b and retVal all to have the same Dtype")
+func (e *Engine) checkValidMatVecMulInput(a, b, ret tensor.Tensor, transA bool) (ad, bd, retVal tensor.DenseTensor, err error) {
+	if err = e.checkLinalgDtype(a, b, ret); err != nil {
+		return nil, nil, nil, err
 	}
 	ad, ok := a.(tensor.DenseTensor)
 	if !ok {
@@ -314,12 +406,19 @@ func (e *Engine) checkValidMatVecMulInput(a, b, ret tensor.Tensor) (ad, bd, retV
 	if !ok {
 		return nil, nil, nil, errors.New("Expected retVal to be a DenseTensor")
 	}
-	if ad.Shape().Dims() != 2 || bd.Shape().IsVectorLike() || retVal.Shape().IsVectorLike() {
-		return nil, nil, nil, errors.New("Expected a to be 2D and b and retVal to be  vectorlike")
+	if ad.Shape().Dims() != 2 || !bd.Shape().IsVectorLike() || !retVal.Shape().IsVectorLike() {
+		return nil, nil, nil, errors.New("Expected a to be 2D and b and retVal to be vectorlike")
 	}
-	if ad.Shape()[1] != bd.Shape()[0] {
+	m, n := ad.Shape()[0], ad.Shape()[1]
+	if transA {
+		m, n = n, m
+	}
+	if getVecLen(bd.Shape()) != n {
 		return nil, nil, nil, errors.New("Expected the inner dimensions of a and b to match")
 	}
+	if getVecLen(retVal.Shape()) != m {
+		return nil, nil, nil, errors.Errorf("Expected retVal to be a vector of %d. Got %v instead", m, retVal.Shape())
+	}
 
 	return ad, bd, retVal, nil
 }
@@ -345,8 +444,11 @@ func (e *Engine) handleFuncOpts(expectedShape tensor.Shape, expectedType tensor.
 			err = errors.Errorf("Expecte shape to be equivalent to %v. Got %v instead", expectedShape, reuse.Shape())
 			return
 		}
-		if err = reuse.Reshape(expectedShape.Clone()...); err != nil {
-			return
+		// reshaping resets the strides, which would turn a view into a different one
+		if !reuse.Shape().Eq(expectedShape) {
+			if err = reuse.Reshape(expectedShape.Clone()...); err != nil {
+				return
+			}
 		}
 	}
 	return
@@ -0,0 +1,55 @@
+//go:build darwin
+// +build darwin
+
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func newTestEngine(t *testing.T, opts ...EngineOpt) *Engine {
+	d := NewDevice()
+	if d == nil {
+		t.Skip("No Metal device found")
+	}
+	return NewEngine(d, opts...)
+}
+
+// MPS takes the rows and columns of the transposed matrix, which differ from those of the stored one only if it isn't square.
+func TestMetalBackend_MatVecMulTransposed(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6, 7, 8}, 2, 4)
+	x := engineTensor(t, e, []float32{1, 2}, 2)
+	y := engineTensor(t, e, make([]float32, 4), 4)
+	al := MatrixLayout{Rows: 2, Cols: 4, RowBytes: 16, Dtype: tensor.Float32}
+	if err := e.b.MatVecMul(bufferOf(t, e, a), bufferOf(t, e, x), bufferOf(t, e, y), al,
+		VectorLayout{Length: 2, Dtype: tensor.Float32}, VectorLayout{Length: 4, Dtype: tensor.Float32}, true); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{11, 14, 17, 20}, readback(t, e, y).Data())
+}
+
+// bufferOf returns the Buffer of the engine memory backing t.
+func bufferOf(t *testing.T, e *Engine, x tensor.Tensor) Buffer {
+	buf, err := e.bufferOf(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// Dot of a vector and a matrix multiplies by the transposed matrix on the device.
+func TestEngine_DotVectorMatrixOnDevice(t *testing.T) {
+	e := newTestEngine(t)
+	x := engineTensor(t, e, []float32{1, 2}, 2)
+	m := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6, 7, 8}, 2, 4)
+	ret, err := e.Dot(x, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{4}, ret.Shape())
+	assert.Equal(t, []float32{11, 14, 17, 20}, readback(t, e, ret).Data())
+}
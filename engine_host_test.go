@@ -0,0 +1,10 @@
+//go:build !darwin
+// +build !darwin
+
+package magol
+
+import "testing"
+
+func newTestEngine(t *testing.T, opts ...EngineOpt) *Engine {
+	return NewEngineWithBackend(NewHostBackend(), opts...)
+}
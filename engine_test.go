@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/chewxy/math32"
 	"github.com/nlpodyssey/spago/mat"
@@ -60,46 +61,60 @@ func allWithinRange(a, b []float32, fn func(a, b float32) bool) bool {
 	return true
 }
 
+// engineTensor creates a tensor backed by engine memory, filled with the given backing.
+func engineTensor(t *testing.T, e *Engine, backing []float32, shape ...int) *tensor.Dense {
+	size := int64(len(backing)) * 4
+	mem, err := e.Alloc(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.b.CopyIn(mem.(Buffer), unsafe.Slice((*byte)(unsafe.Pointer(&backing[0])), size)); err != nil {
+		t.Fatal(err)
+	}
+	return tensor.New(tensor.WithShape(shape...), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(mem.Uintptr(), mem.MemSize()))
+}
+
+// readback copies the data of an engine backed tensor into a Go tensor.
+func readback(t *testing.T, e *Engine, x tensor.Tensor) *tensor.Dense {
+	retVal := tensor.New(tensor.WithShape(x.Shape().Clone()...), tensor.Of(x.Dtype()))
+	if err := e.Memcpy(retVal, x); err != nil {
+		t.Fatal(err)
+	}
+	return retVal
+}
+
 func TestEngine_MatMul(t *testing.T) {
-	d := NewDevice()
-	e := NewEngine(d)
+	e := newTestEngine(t)
 	backingA := []float32{1, 2, 3, 4, 5, 6}
 	backingB := []float32{1, 2, 3, 4, 5, 6, 7, 8, 9}
-	memA := GoSliceAsMBuf(d, backingA)
-	memB := GoSliceAsMBuf(d, backingB)
 
-	a := tensor.New(tensor.WithShape(2, 3), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(memA.Uintptr(), memA.MemSize()))
-	b := tensor.New(tensor.WithShape(3, 3), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(memB.Uintptr(), memB.MemSize()))
+	a := engineTensor(t, e, backingA, 2, 3)
+	b := engineTensor(t, e, backingB, 3, 3)
 	c, err := a.MatMul(b)
 	if err != nil {
 		t.Logf("%v %v %v", a.Dtype(), b.Dtype(), c.Dtype())
 		t.Fatal(err)
 	}
-	CC := tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32))
-	Mbuf2Buf(CC, c)
+	CC := readback(t, e, c)
 	assert.Equal(t, []float32{30, 36, 42, 66, 81, 96}, CC.Data())
 	t.Logf("\n%v", CC)
 
 }
 
 func TestEngine_Add(t *testing.T) {
-	d := NewDevice()
-	e := NewEngine(d)
+	e := newTestEngine(t)
 	backingA := []float32{1, 2, 3, 4, 5, 6}
 	backingB := []float32{1, 2, 3, 4, 5, 6}
-	memA := GoSliceAsMBuf(d, backingA)
-	memB := GoSliceAsMBuf(d, backingB)
 
-	a := tensor.New(tensor.WithShape(2, 3), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(memA.Uintptr(), memA.MemSize()))
-	b := tensor.New(tensor.WithShape(2, 3), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(memB.Uintptr(), memB.MemSize()))
+	a := engineTensor(t, e, backingA, 2, 3)
+	b := engineTensor(t, e, backingB, 2, 3)
 
 	c, err := tensor.Add(a, b)
 	if err != nil {
 		t.Logf("%v %v", a.Dtype(), b.Dtype())
 		t.Fatal(err)
 	}
-	CC := tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32))
-	Mbuf2Buf(CC, c)
+	CC := readback(t, e, c)
 	assert.Equal(t, []float32{2, 4, 6, 8, 10, 12}, CC.Data())
 	t.Logf("\n%v", CC)
 }
@@ -107,18 +122,15 @@ func TestEngine_Add(t *testing.T) {
 func TestEngine_SoftMax(t *testing.T) {
 	r := 2
 	c := 3
-	d := NewDevice()
-	e := NewEngine(d)
+	e := newTestEngine(t)
 
 	backingA := makeRandom(r, c)
-	memA := GoSliceAsMBuf(d, backingA)
-	a := tensor.New(tensor.WithShape(r, c), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(memA.Uintptr(), memA.MemSize()))
-	b, err := tensor.SoftMax(a, 0)
+	a := engineTensor(t, e, backingA, r, c)
+	b, err := tensor.SoftMax(a, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
-	BB := tensor.New(tensor.WithShape(r, c), tensor.Of(tensor.Float32))
-	Mbuf2Buf(BB, b)
+	BB := readback(t, e, b)
 
 	backingX := make([]float32, r*c)
 	copy(backingX, backingA)
@@ -149,3 +161,75 @@ func TestEngine_SoftMax(t *testing.T) {
 	assert.True(t, allWithinRange(BB.Data().([]float32), recombined, veryclosef32), "spago ≠ metal")
 
 }
+
+func TestEngine_Batching(t *testing.T) {
+	assert := assert.New(t)
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb, WithBatchSize(4))
+
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := engineTensor(t, e, []float32{10, 20, 30}, 3)
+
+	// ops are only encoded...
+	c, err := e.Add(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cBuf, err := e.bufferOf(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 1)
+	assert.Equal([]float32{0, 0, 0}, f32s(cBuf), "op ran before the batch was flushed")
+
+	// ... and run in order when the batch is full
+	for i := 0; i < 2; i++ {
+		if _, err = e.Add(c, b, tensor.UseUnsafe()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Len(hb.queue, 3)
+	if _, err = e.Add(c, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 0)
+	assert.Len(e.q.inflight, 1)
+	if err = e.q.inflight[0].Wait(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]float32{41, 82, 123}, f32s(cBuf))
+
+	// ... or when Sync is called
+	if _, err = e.Add(c, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 1)
+	if err = e.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 0)
+	assert.Equal([]float32{51, 102, 153}, f32s(cBuf))
+
+	// ... or when the host reads device memory
+	if _, err = e.Add(c, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(hb.queue, 1)
+	assert.Equal([]float32{61, 122, 183}, readback(t, e, c).Data())
+	assert.Len(hb.queue, 0)
+}
+
+func TestEngine_BatchedChain(t *testing.T) {
+	hb := NewHostBackend()
+	e := NewEngineWithBackend(hb)
+
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := engineTensor(t, e, []float32{1, 1, 1}, 3)
+	for i := 0; i < 50; i++ {
+		if _, err := e.Add(a, b, tensor.UseUnsafe()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Len(t, hb.queue, 50, "a chain shorter than the batch size should be committed at once")
+	assert.Equal(t, []float32{51, 52, 53}, readback(t, e, a).Data())
+}
@@ -1,3 +1,6 @@
+//go:build darwin
+// +build darwin
+
 package magol
 
 import (
@@ -50,7 +53,10 @@ func Run() {
 
 	q := MakeCommandQueue(dev)
 	cmdbuf := q.CommandBuffer()
-	MPSMatMul(cmdbuf, matA, matB, matC)
+	MPSMatMul(cmdbuf, matA, matB, matC, 1, 0)
+	if err := cmdbuf.Commit().Wait(); err != nil {
+		panic(err)
+	}
 	//debug(matC)
 	Mbuf2Buf(C, matC.b)
 	fmt.Printf("%v\n", C)
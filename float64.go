@@ -0,0 +1,116 @@
+package magol
+
+import (
+	"math"
+
+	"gorgonia.org/tensor"
+)
+
+// Metal has no doubles, so there are no kernels for Float64s. An Engine made WithFloat64OnHost runs its ops on Float64 tensors
+// with tensor.StdEng on the host instead, in full double precision.
+//
+// The memory of the Engine is shared by the host and the device, so Float64 tensors stay in it: StdEng works on it in place,
+// once the pending ops are done. Results that StdEng allocates itself, like those of reductions, are in Go memory.
+// Argmax and Argmin return tensor.Ints, whatever the index Dtype of the Engine.
+// Gemm, BatchedMatMul and Where have no StdEng equivalent, and still fail for Float64s, as does Clamp between tensors.
+
+// onHost runs op with tensor.StdEng, and reports that it did, if the Engine was made WithFloat64OnHost and any of ts is a Float64 tensor.
+// The pending ops are waited for first, so that the host sees their results, and they are done with the memory op writes.
+func (e *Engine) onHost(op func(std tensor.StdEng) error, ts ...tensor.Tensor) (bool, error) {
+	if !e.float64OnHost {
+		return false, nil
+	}
+	for _, t := range ts {
+		if t != nil && t.Dtype() == tensor.Float64 {
+			if err := e.Sync(); err != nil {
+				return true, err
+			}
+			return true, op(tensor.StdEng{})
+		}
+	}
+	return false, nil
+}
+
+// stdBinOps, stdScalarOps, stdUnaryOps, stdReduceOps, stdArgOps, stdCmpOps and stdCmpScalarOps are the StdEng equivalents of the kernels of the ops.
+var (
+	stdBinOps = map[string]func(tensor.StdEng, tensor.Tensor, tensor.Tensor, ...tensor.FuncOpt) (tensor.Tensor, error){
+		"add": tensor.StdEng.Add,
+		"sub": tensor.StdEng.Sub,
+		"mul": tensor.StdEng.Mul,
+		"div": tensor.StdEng.Div,
+		"pow": tensor.StdEng.Pow,
+		"mod": tensor.StdEng.Mod,
+		"min": tensor.StdEng.MinBetween,
+		"max": tensor.StdEng.MaxBetween,
+	}
+	stdScalarOps = map[string]func(tensor.StdEng, tensor.Tensor, interface{}, bool, ...tensor.FuncOpt) (tensor.Tensor, error){
+		"add": tensor.StdEng.AddScalar,
+		"sub": tensor.StdEng.SubScalar,
+		"mul": tensor.StdEng.MulScalar,
+		"div": tensor.StdEng.DivScalar,
+		"pow": tensor.StdEng.PowScalar,
+		"mod": tensor.StdEng.ModScalar,
+		"min": tensor.StdEng.MinBetweenScalar,
+		"max": tensor.StdEng.MaxBetweenScalar,
+	}
+	stdUnaryOps = map[string]func(tensor.StdEng, tensor.Tensor, ...tensor.FuncOpt) (tensor.Tensor, error){
+		"exp":     tensor.StdEng.Exp,
+		"log":     tensor.StdEng.Log,
+		"sqrt":    tensor.StdEng.Sqrt,
+		"rsqrt":   tensor.StdEng.InvSqrt,
+		"tanh":    tensor.StdEng.Tanh,
+		"sigmoid": sigmoidOnHost,
+		"abs":     tensor.StdEng.Abs,
+		"neg":     tensor.StdEng.Neg,
+		"sign":    tensor.StdEng.Sign,
+		"square":  tensor.StdEng.Square,
+		"cube":    tensor.StdEng.Cube,
+	}
+	stdReduceOps = map[string]func(tensor.StdEng, tensor.Tensor, ...int) (tensor.Tensor, error){
+		"sum":  tensor.StdEng.Sum,
+		"prod": prodOnHost,
+		"max":  tensor.StdEng.Max,
+		"min":  tensor.StdEng.Min,
+	}
+	stdArgOps = map[string]func(tensor.StdEng, tensor.Tensor, int) (tensor.Tensor, error){
+		"argmax": tensor.StdEng.Argmax,
+		"argmin": tensor.StdEng.Argmin,
+	}
+	stdCmpOps = map[string]func(tensor.StdEng, tensor.Tensor, tensor.Tensor, ...tensor.FuncOpt) (tensor.Tensor, error){
+		"gt":  tensor.StdEng.Gt,
+		"gte": tensor.StdEng.Gte,
+		"lt":  tensor.StdEng.Lt,
+		"lte": tensor.StdEng.Lte,
+		"eq":  tensor.StdEng.ElEq,
+		"ne":  tensor.StdEng.ElNe,
+	}
+	stdCmpScalarOps = map[string]func(tensor.StdEng, tensor.Tensor, interface{}, bool, ...tensor.FuncOpt) (tensor.Tensor, error){
+		"gt":  tensor.StdEng.GtScalar,
+		"gte": tensor.StdEng.GteScalar,
+		"lt":  tensor.StdEng.LtScalar,
+		"lte": tensor.StdEng.LteScalar,
+		"eq":  tensor.StdEng.EqScalar,
+		"ne":  tensor.StdEng.NeScalar,
+	}
+)
+
+// sigmoidOnHost computes the logistic function of the Float64s of a, which StdEng has no method for.
+func sigmoidOnHost(std tensor.StdEng, a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return std.Map(func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }, a, opts...)
+}
+
+// prodOnHost multiplies the elements of a along the given axes, or along all of them if none are given, which StdEng has no method for.
+func prodOnHost(std tensor.StdEng, a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	axes, err := reducedAxes(a.Shape(), along)
+	if err != nil {
+		return nil, err
+	}
+	retVal := a
+	// reducing the last axes first keeps the others where they are
+	for i := len(axes) - 1; i >= 0; i-- {
+		if retVal, err = std.Reduce(func(x, y float64) float64 { return x * y }, retVal, axes[i], float64(1)); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
@@ -0,0 +1,121 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+// float64s returns the data of a Float64 tensor as a slice, even if it is a scalar.
+func float64s(data interface{}) []float64 {
+	if f, ok := data.(float64); ok {
+		return []float64{f}
+	}
+	return data.([]float64)
+}
+
+// engineFloat64s creates a Float64 engine tensor holding data.
+func engineFloat64s(t *testing.T, e *Engine, data []float64, shape ...int) *tensor.Dense {
+	return engineCopy(t, e, tensor.New(tensor.WithShape(shape...), tensor.WithBacking(append([]float64(nil), data...))))
+}
+
+func TestEngine_Float64OnHost(t *testing.T) {
+	// the elements differ from each other by much less than a float32 ulp
+	aData := []float64{1, 1 + 1e-12, 1 + 2e-12, -2, 0.5, 3e-9}
+	bData := []float64{1e-13, 2, -1 + 1e-15, 4, 0.25, 1}
+	cases := []struct {
+		name string
+		op   func(e tensor.Engine, a, b tensor.Tensor) (tensor.Tensor, error)
+	}{
+		{"Add", func(e tensor.Engine, a, b tensor.Tensor) (tensor.Tensor, error) { return e.(tensor.Adder).Add(a, b) }},
+		{"Mul", func(e tensor.Engine, a, b tensor.Tensor) (tensor.Tensor, error) { return e.(tensor.Muler).Mul(a, b) }},
+		{"SubScalar", func(e tensor.Engine, a, _ tensor.Tensor) (tensor.Tensor, error) {
+			return e.(tensor.Suber).SubScalar(a, float64(1), true)
+		}},
+		{"Gt", func(e tensor.Engine, a, b tensor.Tensor) (tensor.Tensor, error) { return e.(tensor.Gter).Gt(a, b) }},
+		{"Exp", func(e tensor.Engine, a, _ tensor.Tensor) (tensor.Tensor, error) { return e.(tensor.Exper).Exp(a) }},
+		{"Sum", func(e tensor.Engine, a, _ tensor.Tensor) (tensor.Tensor, error) { return e.(tensor.Sumer).Sum(a, 1) }},
+		{"Argmax", func(e tensor.Engine, a, _ tensor.Tensor) (tensor.Tensor, error) {
+			return e.(tensor.Argmaxer).Argmax(a, 0)
+		}},
+		{"SoftMax", func(e tensor.Engine, a, _ tensor.Tensor) (tensor.Tensor, error) {
+			return e.(tensor.SoftMaxer).SoftMax(a, 1)
+		}},
+		{"MatMul", func(e tensor.Engine, a, b tensor.Tensor) (tensor.Tensor, error) {
+			bt := b.Clone().(*tensor.Dense)
+			if err := bt.T(); err != nil {
+				return nil, err
+			}
+			return e.(tensor.Dotter).Dot(a, bt)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			want, err := c.op(tensor.StdEng{}, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float64(nil), aData...))),
+				tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float64(nil), bData...))))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := newTestEngine(t, WithFloat64OnHost())
+			a, b := engineFloat64s(t, e, aData, 2, 3), engineFloat64s(t, e, bData, 2, 3)
+			got, err := c.op(e, a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want.Shape(), got.Shape())
+			assert.Equal(t, want.Data(), got.Data(), "the results should be those of StdEng exactly")
+		})
+	}
+}
+
+func TestEngine_Float64Mixed(t *testing.T) {
+	e := newTestEngine(t, WithFloat64OnHost())
+	x := engineFloat64s(t, e, []float64{1, 2, 3}, 3)
+
+	// float32 ops are still run by kernels, before and after Float64 ones
+	f := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	if _, err := e.Add(f, f, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Mul(x, x, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Add(f, f, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{4, 8, 12}, readback(t, e, f).Data())
+	assert.Equal(t, []float64{1, 4, 9}, readback(t, e, x).Data())
+
+	// WithIncr and Mean, which is composed of a reduction and a division
+	incr := engineFloat64s(t, e, []float64{1, 1, 1}, 3)
+	if _, err := e.Add(x, x, tensor.WithIncr(incr)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{3, 9, 19}, readback(t, e, incr).Data())
+	mean, err := e.Mean(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.InDelta(t, 14.0/3, float64s(mean.Data())[0], 1e-15)
+
+	prod, err := e.Prod(engineFloat64s(t, e, []float64{1, 2, 3, 4, 5, 6}, 2, 3), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{6, 120}, prod.Data())
+	ip, err := e.Inner(x, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, float64(98), ip)
+
+	_, err = e.Add(x, f)
+	assert.Error(t, err, "mixing dtypes")
+
+	// without the option, Float64s are not supported
+	e2 := newTestEngine(t)
+	_, err = e2.Add(engineFloat64s(t, e2, []float64{1}, 1), engineFloat64s(t, e2, []float64{1}, 1))
+	assert.Error(t, err)
+}
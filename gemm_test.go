@@ -0,0 +1,176 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+// transposed returns the row major data of the transpose of the row major (rows, cols) matrix data.
+func transposed(data []float32, rows, cols int) []float32 {
+	retVal := make([]float32, len(data))
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			retVal[j*rows+i] = data[i*cols+j]
+		}
+	}
+	return retVal
+}
+
+// gemmOperand returns an engine tensor x such that op(x) is the row major (rows, cols) matrix data,
+// where op transposes if trans is set. x is a transposed view if view is set.
+func gemmOperand(t *testing.T, e *Engine, data []float32, rows, cols int, trans, view bool) *tensor.Dense {
+	switch {
+	case trans && view:
+		// the transpose of a transposed view of data is data
+		x := engineTensor(t, e, data, rows, cols)
+		if err := x.T(); err != nil {
+			t.Fatal(err)
+		}
+		return x
+	case trans:
+		return engineTensor(t, e, transposed(data, rows, cols), cols, rows)
+	case view:
+		x := engineTensor(t, e, transposed(data, rows, cols), cols, rows)
+		if err := x.T(); err != nil {
+			t.Fatal(err)
+		}
+		return x
+	}
+	return engineTensor(t, e, data, rows, cols)
+}
+
+func TestEngine_Gemm(t *testing.T) {
+	const m, k, n = 3, 4, 5
+	const alpha, beta = 2, 0.5
+	aData, bData, cData := makeRandom(m, k), makeRandom(k, n), makeRandom(m, n)
+	product := naiveBatchedMatMul(aData, bData, 1, m, k, n, false)
+	want := make([]float32, m*n)
+	for i := range want {
+		want[i] = alpha*product[i] + beta*cData[i]
+	}
+
+	for _, transA := range []bool{false, true} {
+		for _, transB := range []bool{false, true} {
+			for _, view := range []bool{false, true} {
+				for _, cView := range []bool{false, true} {
+					e := newTestEngine(t)
+					a := gemmOperand(t, e, aData, m, k, transA, view)
+					b := gemmOperand(t, e, bData, k, n, transB, view)
+					c := gemmOperand(t, e, cData, m, n, false, cView)
+					if err := e.Gemm(transA, transB, alpha, a, b, beta, c); err != nil {
+						t.Fatal(err)
+					}
+					mc, err := e.Materialize(c)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.True(t, allWithinRange(want, readback(t, e, mc).Data().([]float32), closef32),
+						"transA: %v, transB: %v, views: %v, transposed C: %v", transA, transB, view, cView)
+				}
+			}
+		}
+	}
+}
+
+func TestEngine_GemmAccumulate(t *testing.T) {
+	// the gradient of the weights of y = x·w, for a batch of 3 4-vectors x and dy of 2-vectors, is xᵀ·dy
+	const batch, in, out = 3, 4, 2
+	x, dy := makeRandom(batch, in), makeRandom(batch, out)
+	grad := naiveBatchedMatMul(transposed(x, batch, in), dy, 1, in, batch, out, false)
+
+	e := newTestEngine(t)
+	xt, dyt := engineTensor(t, e, x, batch, in), engineTensor(t, e, dy, batch, out)
+	dw := engineTensor(t, e, make([]float32, in*out), in, out)
+	for i := 0; i < 2; i++ {
+		if err := e.Gemm(true, false, 1, xt, dyt, 1, dw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := make([]float32, len(grad))
+	for i := range want {
+		want[i] = 2 * grad[i]
+	}
+	assert.True(t, allWithinRange(want, readback(t, e, dw).Data().([]float32), closef32))
+}
+
+func TestEngine_GemmErrors(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, make([]float32, 6), 2, 3)
+	b := engineTensor(t, e, make([]float32, 12), 3, 4)
+	c := engineTensor(t, e, make([]float32, 8), 2, 4)
+
+	// op(A) is (3, 2), which doesn't fit B
+	assert.Error(t, e.Gemm(true, false, 1, a, b, 0, c))
+	// op(B) is (4, 3), which doesn't fit A
+	assert.Error(t, e.Gemm(false, true, 1, a, b, 0, c))
+	// C isn't (2, 4)
+	assert.Error(t, e.Gemm(false, false, 1, a, b, 0, engineTensor(t, e, make([]float32, 8), 4, 2)))
+	assert.Error(t, e.MatMul(a, b, engineTensor(t, e, make([]float32, 6), 2, 3)))
+	// not float32
+	assert.Error(t, e.Gemm(false, false, 1, a, b, 0, tensor.New(tensor.WithShape(2, 4), tensor.Of(tensor.Float64))))
+
+	// the temporary result, made as C is strided along both axes, is released when the op fails, here as A is not in the memory of the Engine
+	wide := engineTensor(t, e, make([]float32, 16), 2, 8)
+	strided, err := wide.Slice(nil, tensor.S(0, 8, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := liveAllocs(e)
+	assert.Error(t, e.Gemm(false, false, 1, tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)), b, 1, strided), "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the temporary result should have been released")
+}
+
+func TestEngine_Incr(t *testing.T) {
+	data := []float32{-1, 0, 1, 2, 3, 4}
+	cases := []struct {
+		name string
+		op   func(e *Engine, x, incr tensor.Tensor) (tensor.Tensor, error)
+		std  func(x tensor.Tensor) (tensor.Tensor, error)
+	}{
+		{"Add",
+			func(e *Engine, x, incr tensor.Tensor) (tensor.Tensor, error) {
+				return e.Add(x, x, tensor.WithIncr(incr))
+			},
+			func(x tensor.Tensor) (tensor.Tensor, error) { return tensor.Add(x, x) }},
+		{"MulScalar",
+			func(e *Engine, x, incr tensor.Tensor) (tensor.Tensor, error) {
+				return e.MulScalar(x, float32(3), true, tensor.WithIncr(incr))
+			},
+			func(x tensor.Tensor) (tensor.Tensor, error) { return tensor.Mul(x, float32(3)) }},
+		{"Exp",
+			func(e *Engine, x, incr tensor.Tensor) (tensor.Tensor, error) {
+				return e.Exp(x, tensor.WithIncr(incr))
+			},
+			func(x tensor.Tensor) (tensor.Tensor, error) { return tensor.Exp(x) }},
+		{"SoftMax",
+			func(e *Engine, x, incr tensor.Tensor) (tensor.Tensor, error) {
+				return e.SoftMax(x, 1, tensor.WithIncr(incr))
+			},
+			func(x tensor.Tensor) (tensor.Tensor, error) { return tensor.SoftMax(x, 1) }},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestEngine(t)
+			x := engineTensor(t, e, data, 2, 3)
+			incr := engineTensor(t, e, []float32{1, 1, 1, 1, 1, 1}, 2, 3)
+			ret, err := c.op(e, x, incr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, ret == incr, "expected the result to be the incr tensor")
+
+			expected, err := c.std(stdTensor(data, 2, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expected.Data().([]float32)
+			for i := range want {
+				want[i]++
+			}
+			assert.True(t, allWithinRange(want, readback(t, e, incr).Data().([]float32), closef32))
+			assert.Equal(t, data, readback(t, e, x).Data())
+		})
+	}
+}
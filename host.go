@@ -0,0 +1,788 @@
+package magol
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/chewxy/math32"
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+var _ Backend = &HostBackend{}
+
+// HostBackend is a Backend that runs on plain Go memory.
+//
+// It has the same semantics as the Metal backend, and is used to run the Engine on platforms without Metal.
+// In particular, ops are not run when they are encoded, but queued up until Commit is called.
+// Each committed batch is then run on its own goroutine, after the previously committed batch.
+type HostBackend struct {
+	sync.Mutex
+	live  map[uintptr][]uint64 // keeps allocations alive until they are freed
+	queue []func()             // encoded but not yet committed ops
+	last  *Completion          // the last committed batch
+}
+
+// NewHostBackend creates a new HostBackend.
+func NewHostBackend() *HostBackend {
+	return &HostBackend{live: make(map[uintptr][]uint64)}
+}
+
+func (b *HostBackend) Alloc(size int64) (Buffer, error) {
+	if size < 0 {
+		return Buffer{}, errors.Errorf("Cannot allocate %d bytes", size)
+	}
+	// allocating words keeps every allocation aligned for all supported dtypes
+	words := (size + 7) / 8
+	if words == 0 {
+		words = 1
+	}
+	mem := make([]uint64, words)
+	ptr := unsafe.Pointer(&mem[0])
+
+	b.Lock()
+	b.live[uintptr(ptr)] = mem
+	b.Unlock()
+	return Buffer{b: ptr, ptr: ptr, sz: uintptr(size)}, nil
+}
+
+func (b *HostBackend) Free(buf Buffer) error {
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.live[uintptr(buf.b)]; !ok {
+		return errors.Errorf("Buffer %#x was not allocated by this backend", uintptr(buf.b))
+	}
+	delete(b.live, uintptr(buf.b))
+	return nil
+}
+
+func (b *HostBackend) CopyIn(dst Buffer, src []byte) error {
+	if uintptr(len(src)) > dst.sz {
+		return errors.Errorf("Cannot copy %d bytes into a Buffer of %d bytes", len(src), dst.sz)
+	}
+	copy(hostBytes(dst), src)
+	return nil
+}
+
+func (b *HostBackend) CopyOut(dst []byte, src Buffer) error {
+	if uintptr(len(dst)) > src.sz {
+		return errors.Errorf("Cannot copy %d bytes out of a Buffer of %d bytes", len(dst), src.sz)
+	}
+	copy(dst, hostBytes(src))
+	return nil
+}
+
+func (b *HostBackend) Copy(dst, src Buffer) error {
+	if src.sz > dst.sz {
+		return errors.Errorf("Cannot copy %d bytes into a Buffer of %d bytes", src.sz, dst.sz)
+	}
+	copy(hostBytes(dst), hostBytes(src))
+	return nil
+}
+
+func (b *HostBackend) Fill(dst Buffer, pattern []byte) error {
+	if len(pattern) == 0 {
+		return errors.New("Cannot fill a Buffer with an empty pattern")
+	}
+	fillBytes(hostBytes(dst), pattern)
+	return nil
+}
+
+func (b *HostBackend) Dispatch(kernel string, n int, consts [][]byte, args ...Buffer) error {
+	return b.dispatch(kernel, false, n, consts, args)
+}
+
+func (b *HostBackend) DispatchGroups(kernel string, groups int, consts [][]byte, args ...Buffer) error {
+	return b.dispatch(kernel, true, groups, consts, args)
+}
+
+func (b *HostBackend) dispatch(kernel string, grouped bool, n int, consts [][]byte, args []Buffer) error {
+	k, ok := hostKernels[kernel]
+	if !ok {
+		return errors.Errorf("Kernel %q not found", kernel)
+	}
+	if k.grouped != grouped {
+		return errors.Errorf("Kernel %q can not be dispatched this way", kernel)
+	}
+	if len(args) != k.args {
+		return errors.Errorf("Expected %d buffers for %q. Got %d instead", k.args, kernel, len(args))
+	}
+	// like Metal, the constants are captured at encode time
+	captured := make([][]byte, len(consts))
+	for i, c := range consts {
+		captured[i] = append([]byte(nil), c...)
+	}
+	b.enqueue(func() { k.run(n, args, captured) })
+	return nil
+}
+
+func (b *HostBackend) MatMul(a, bb, c Buffer, al, bl, cl MatrixLayout, transA, transB bool, alpha, beta float32) error {
+	m, k := al.Rows, al.Cols
+	if transA {
+		m, k = k, m
+	}
+	k2, n := bl.Rows, bl.Cols
+	if transB {
+		k2, n = n, k2
+	}
+	if k != k2 || cl.Rows != m || cl.Cols != n {
+		return errors.Errorf("Cannot multiply a (%d, %d) matrix by a (%d, %d) matrix into a (%d, %d) matrix", m, k, k2, n, cl.Rows, cl.Cols)
+	}
+	batches := cl.batches()
+	if al.batches() != batches || bl.batches() != batches {
+		return errors.Errorf("Cannot multiply a batch of %d matrices by a batch of %d matrices into a batch of %d matrices", al.batches(), bl.batches(), batches)
+	}
+	b.enqueue(func() {
+		for batch := 0; batch < batches; batch++ {
+			A, B, C := hostBatchMatrix(a, al, batch), hostBatchMatrix(bb, bl, batch), hostBatchMatrix(c, cl, batch)
+			for i := 0; i < m; i++ {
+				for j := 0; j < n; j++ {
+					var sum float32
+					for l := 0; l < k; l++ {
+						sum += A.at(i, l, transA) * B.at(l, j, transB)
+					}
+					v := alpha * sum
+					if beta != 0 {
+						v += beta * C.at(i, j, false)
+					}
+					C.set(i, j, v)
+				}
+			}
+		}
+	})
+	return nil
+}
+
+func (b *HostBackend) MatVecMul(a, x, y Buffer, al MatrixLayout, xl, yl VectorLayout, transA bool) error {
+	m, n := al.Rows, al.Cols
+	if transA {
+		m, n = n, m
+	}
+	if xl.Length != n || yl.Length != m {
+		return errors.Errorf("Cannot multiply a (%d, %d) matrix by a vector of %d into a vector of %d", m, n, xl.Length, yl.Length)
+	}
+	A := hostMatrix(a, al)
+	X, Y := hostElemsOf(x, xl.Dtype), hostElemsOf(y, yl.Dtype)
+	b.enqueue(func() {
+		for i := 0; i < m; i++ {
+			var sum float32
+			for j := 0; j < n; j++ {
+				sum += A.at(i, j, transA) * X.get(j)
+			}
+			Y.set(i, sum)
+		}
+	})
+	return nil
+}
+
+func (b *HostBackend) SoftMax(x, out Buffer, xl, outl MatrixLayout) error {
+	if xl.Rows != outl.Rows || xl.Cols != outl.Cols {
+		return errors.Errorf("Cannot softmax a (%d, %d) matrix into a (%d, %d) matrix", xl.Rows, xl.Cols, outl.Rows, outl.Cols)
+	}
+	X, Out := hostMatrix(x, xl), hostMatrix(out, outl)
+	b.enqueue(func() {
+		for i := 0; i < xl.Rows; i++ {
+			max := math32.Inf(-1)
+			for j := 0; j < xl.Cols; j++ {
+				max = math32.Max(max, X.at(i, j, false))
+			}
+			var sum float32
+			for j := 0; j < xl.Cols; j++ {
+				v := math32.Exp(X.at(i, j, false) - max)
+				Out.set(i, j, v)
+				sum += v
+			}
+			for j := 0; j < xl.Cols; j++ {
+				Out.set(i, j, Out.at(i, j, false)/sum)
+			}
+		}
+	})
+	return nil
+}
+
+// Commit runs all queued ops, in the order they were encoded, once the previously committed batch is done.
+func (b *HostBackend) Commit() *Completion {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.queue) == 0 {
+		return completedCompletion()
+	}
+	queue, prev := b.queue, b.last
+	c := newCompletion()
+	c.setStatus(StatusCommitted)
+	b.queue, b.last = nil, c
+
+	go func() {
+		if prev != nil {
+			<-prev.Done()
+		}
+		c.setStatus(StatusScheduled)
+		c.complete(runHostOps(queue))
+	}()
+	return c
+}
+
+// Discard drops all queued ops.
+func (b *HostBackend) Discard() {
+	b.Lock()
+	b.queue = nil
+	b.Unlock()
+}
+
+// runHostOps runs the ops, reporting a panic in any of them as a failed command buffer.
+func runHostOps(ops []func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &CommandBufferError{Code: ErrCodeInternal, Msg: fmt.Sprint(r)}
+		}
+	}()
+	for _, op := range ops {
+		op()
+	}
+	return nil
+}
+
+func (b *HostBackend) enqueue(op func()) {
+	b.Lock()
+	b.queue = append(b.queue, op)
+	b.Unlock()
+}
+
+func hostBytes(buf Buffer) []byte { return unsafe.Slice((*byte)(buf.ptr), buf.sz) }
+
+// hostMat is a row major matrix in host memory, whose elements are read and written as float32s.
+type hostMat struct {
+	data   hostElems[float32]
+	stride int // in elements
+}
+
+func hostMatrix(buf Buffer, l MatrixLayout) hostMat {
+	return hostMat{data: hostElemsOf(buf, l.Dtype), stride: l.RowBytes / int(l.Dtype.Size())}
+}
+
+// hostBatchMatrix returns the given matrix of the batch described by l.
+func hostBatchMatrix(buf Buffer, l MatrixLayout, matrix int) hostMat {
+	off := uintptr(matrix * l.MatrixBytes)
+	return hostMatrix(buf.view(off, buf.sz-off), l)
+}
+
+func (m hostMat) at(i, j int, trans bool) float32 {
+	if trans {
+		i, j = j, i
+	}
+	return m.data.get(i*m.stride + j)
+}
+
+func (m hostMat) set(i, j int, v float32) { m.data.set(i*m.stride+j, v) }
+
+// hostKernel is the host equivalent of a kernel in the Metal library.
+type hostKernel struct {
+	args    int  // the number of buffers the kernel takes
+	grouped bool // whether the kernel is run by DispatchGroups, in which case n is the number of threadgroups
+	run     func(n int, args []Buffer, consts [][]byte)
+}
+
+// hostBinOps are the host references of binOps.
+var hostBinOps = map[string]func(a, b float32) float32{
+	"add": func(a, b float32) float32 { return a + b },
+	"sub": func(a, b float32) float32 { return a - b },
+	"mul": func(a, b float32) float32 { return a * b },
+	"div": func(a, b float32) float32 { return a / b },
+	"pow": math32.Pow,
+	"mod": math32.Mod,
+	"min": math32.Min,
+	"max": math32.Max,
+}
+
+// hostIntBinOps are the host references of the IntExprs of binOps.
+var hostIntBinOps = map[string]func(a, b int64) int64{
+	"add": func(a, b int64) int64 { return a + b },
+	"sub": func(a, b int64) int64 { return a - b },
+	"mul": func(a, b int64) int64 { return a * b },
+	"div": func(a, b int64) int64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	},
+	"mod": func(a, b int64) int64 {
+		if b == 0 {
+			return 0
+		}
+		return a % b
+	},
+	"min": func(a, b int64) int64 {
+		if b < a {
+			return b
+		}
+		return a
+	},
+	"max": func(a, b int64) int64 {
+		if b > a {
+			return b
+		}
+		return a
+	},
+}
+
+// hostUnaryOps are the host references of copyOp and unaryOps.
+var hostUnaryOps = map[string]func(a float32) float32{
+	"copy":    func(a float32) float32 { return a },
+	"exp":     math32.Exp,
+	"log":     math32.Log,
+	"sqrt":    math32.Sqrt,
+	"rsqrt":   func(a float32) float32 { return 1 / math32.Sqrt(a) },
+	"tanh":    math32.Tanh,
+	"sigmoid": func(a float32) float32 { return 1 / (1 + math32.Exp(-a)) },
+	"abs":     math32.Abs,
+	"neg":     func(a float32) float32 { return -a },
+	"sign":    hostSign,
+	"square":  func(a float32) float32 { return a * a },
+	"cube":    func(a float32) float32 { return a * a * a },
+}
+
+// hostIntUnaryOps are the host references of copyOp and the IntExprs of unaryOps.
+var hostIntUnaryOps = map[string]func(a int64) int64{
+	"copy": func(a int64) int64 { return a },
+	"abs": func(a int64) int64 {
+		if a < 0 {
+			return -a
+		}
+		return a
+	},
+	"neg": func(a int64) int64 { return -a },
+	"sign": func(a int64) int64 {
+		switch {
+		case a > 0:
+			return 1
+		case a < 0:
+			return -1
+		}
+		return 0
+	},
+	"square": func(a int64) int64 { return a * a },
+	"cube":   func(a int64) int64 { return a * a * a },
+}
+
+// hostCmpOps returns the host references of cmpOps, comparing Ts.
+func hostCmpOps[T hostNum]() map[string]func(a, b T) bool {
+	return map[string]func(a, b T) bool{
+		"gt":  func(a, b T) bool { return a > b },
+		"gte": func(a, b T) bool { return a >= b },
+		"lt":  func(a, b T) bool { return a < b },
+		"lte": func(a, b T) bool { return a <= b },
+		"eq":  func(a, b T) bool { return a == b },
+		"ne":  func(a, b T) bool { return a != b },
+	}
+}
+
+// hostSign is MSL's sign: ±0 keep their sign, and NaNs become 0.
+func hostSign(a float32) float32 {
+	switch {
+	case a > 0:
+		return 1
+	case a < 0:
+		return -1
+	case a != a:
+		return 0
+	}
+	return a
+}
+
+// hostReduceOps are the host references of reduceOps.
+var hostReduceOps = map[string]struct {
+	fn       func(a, b float32) float32
+	identity float32
+}{
+	"sum":  {func(a, b float32) float32 { return a + b }, 0},
+	"prod": {func(a, b float32) float32 { return a * b }, 1},
+	"max":  {hostMax, math32.Inf(-1)},
+	"min":  {hostMin, math32.Inf(1)},
+}
+
+// hostMax is MSL's max: if one of a and b is a NaN, the other is returned.
+func hostMax(a, b float32) float32 {
+	if a != a || b > a {
+		return b
+	}
+	return a
+}
+
+// hostMin is MSL's min: if one of a and b is a NaN, the other is returned.
+func hostMin(a, b float32) float32 {
+	if a != a || b < a {
+		return b
+	}
+	return a
+}
+
+// hostArgOps are the host references of the Exprs of argOps.
+var hostArgOps = map[string]func(a, b float32) bool{
+	"argmax": func(a, b float32) bool { return a > b },
+	"argmin": func(a, b float32) bool { return a < b },
+}
+
+// noIndex is NO_INDEX in MSL: the index of no candidate.
+const noIndex = -1
+
+var hostKernels = func() map[string]hostKernel {
+	m := make(map[string]hostKernel)
+	for _, dt := range kernelDtypes {
+		name, _ := permuteName(dt)
+		m[name] = hostKernel{2, true, hostPermuteKernel(int(dt.Size()))}
+		switch {
+		case isInt(dt):
+			addHostElementwise(m, dt, hostIntsOf, hostIntBinOps, hostIntUnaryOps)
+			continue
+		case dt == tensor.Bool:
+			addHostElementwise(m, dt, hostIntsOf, nil, map[string]func(a int64) int64{"copy": hostIntUnaryOps["copy"]})
+			continue
+		}
+		addHostElementwise(m, dt, hostElemsOf, hostBinOps, hostUnaryOps)
+		for op, r := range hostReduceOps {
+			name, _ := reduceName(op, dt)
+			m[name] = hostKernel{2, true, hostReduceKernel(r.fn, r.identity, dt)}
+		}
+		for op, better := range hostArgOps {
+			for _, index := range indexDtypes {
+				name, _ := argName(op, index, dt)
+				m[name] = hostKernel{2, true, hostArgKernel(better, index, dt)}
+			}
+		}
+	}
+	return m
+}()
+
+// addHostElementwise adds the host kernels of the elementwise ops and comparisons of dt to m.
+// Elements of dt are computed with as Ts, and read and written by elemsOf.
+func addHostElementwise[T hostNum](m map[string]hostKernel, dt tensor.Dtype, elemsOf func(Buffer, tensor.Dtype) hostElems[T], binOps map[string]func(a, b T) T, unaryOps map[string]func(a T) T) {
+	cmps := hostCmpOps[T]()
+	for _, strided := range []bool{false, true} {
+		for op, fn := range binOps {
+			name, _ := stridedName(op, strided, dt)
+			m[name] = hostKernel{3, false, hostBinKernel(fn, strided, elemsOf, dt, elemsOf, dt)}
+			for _, left := range []bool{true, false} {
+				name, _ = scalarKernelName(op, left, strided, dt)
+				m[name] = hostKernel{2, false, hostScalarKernel(fn, left, strided, elemsOf, dt, elemsOf, dt)}
+			}
+		}
+		for op, fn := range unaryOps {
+			name, _ := stridedName(op, strided, dt)
+			m[name] = hostKernel{2, false, hostUnaryKernel(fn, strided, elemsOf, dt)}
+		}
+		for _, op := range cmpOps {
+			if dt == tensor.Bool && !op.Bools {
+				continue
+			}
+			cmp := cmps[op.Name]
+			for _, result := range cmpResults(dt) {
+				if result == tensor.Bool {
+					addHostCmp(m, op.Name, cmp, strided, elemsOf, dt, hostIntsOf, result)
+				} else {
+					addHostCmp(m, op.Name, cmp, strided, elemsOf, dt, elemsOf, result)
+				}
+			}
+		}
+		for _, cond := range whereConds(dt) {
+			name, _ := whereName(strided, cond, dt)
+			if cond == tensor.Bool {
+				m[name] = hostKernel{4, false, hostTernaryKernel(hostWhere[int64, T], strided, hostIntsOf, cond, elemsOf, dt)}
+			} else {
+				m[name] = hostKernel{4, false, hostTernaryKernel(hostWhere[T, T], strided, elemsOf, cond, elemsOf, dt)}
+			}
+		}
+		if clampOp.exprOf(dt) != "" {
+			name, _ := stridedName(clampOp.Name, strided, dt)
+			m[name] = hostKernel{4, false, hostTernaryKernel(hostClamp[T], strided, elemsOf, dt, elemsOf, dt)}
+			name, _ = stridedName(clampOp.Name+"_vs", strided, dt)
+			m[name] = hostKernel{2, false, hostTernaryScalarKernel(hostClamp[T], strided, elemsOf, dt)}
+		}
+	}
+}
+
+// hostWhere is the host reference of whereOp: b if a isn't 0, c otherwise.
+func hostWhere[A, T hostNum](a A, b, c T) T {
+	if a != 0 {
+		return b
+	}
+	return c
+}
+
+// hostClamp is the host reference of clampOp.
+func hostClamp[T hostNum](a, b, c T) T {
+	switch {
+	case a < b:
+		return b
+	case a > c:
+		return c
+	}
+	return a
+}
+
+// addHostCmp adds the host kernels of the comparison op of elements of dt, returning elements of the dtype result, to m.
+func addHostCmp[T, R hostNum](m map[string]hostKernel, op string, cmp func(a, b T) bool, strided bool, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype, resultsOf func(Buffer, tensor.Dtype) hostElems[R], result tensor.Dtype) {
+	fn := func(a, b T) R {
+		if cmp(a, b) {
+			return 1
+		}
+		return 0
+	}
+	name, _ := cmpName(op, false, false, strided, result, dt)
+	m[name] = hostKernel{3, false, hostBinKernel(fn, strided, elemsOf, dt, resultsOf, result)}
+	for _, left := range []bool{true, false} {
+		name, _ = cmpName(op, true, left, strided, result, dt)
+		m[name] = hostKernel{2, false, hostScalarKernel(fn, left, strided, elemsOf, dt, resultsOf, result)}
+	}
+}
+
+// hostBinKernel runs fn on elements of dt, read by elemsOf, into elements of the dtype result, written by resultsOf.
+func hostBinKernel[T, R hostNum](fn func(a, b T) R, strided bool, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype, resultsOf func(Buffer, tensor.Dtype) hostElems[R], result tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(n int, args []Buffer, consts [][]byte) {
+		offsets := hostOffsets(strided, consts)
+		a, b, c := elemsOf(args[0], dt), elemsOf(args[1], dt), resultsOf(args[2], result)
+		for i := 0; i < n; i++ {
+			off := offsets(i)
+			c.set(off[maxOperands], fn(a.get(off[0]), b.get(off[1])))
+		}
+	}
+}
+
+func hostScalarKernel[T, R hostNum](fn func(a, b T) R, leftTensor, strided bool, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype, resultsOf func(Buffer, tensor.Dtype) hostElems[R], result tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(n int, args []Buffer, consts [][]byte) {
+		offsets := hostOffsets(strided, consts[1:])
+		in, c := elemsOf(args[0], dt), resultsOf(args[1], result)
+		scalar := consts[0]
+		s := elemsOf(Buffer{ptr: unsafe.Pointer(&scalar[0]), sz: uintptr(len(scalar))}, dt).get(0)
+		for i := 0; i < n; i++ {
+			off := offsets(i)
+			if leftTensor {
+				c.set(off[maxOperands], fn(in.get(off[0]), s))
+			} else {
+				c.set(off[maxOperands], fn(s, in.get(off[0])))
+			}
+		}
+	}
+}
+
+func hostUnaryKernel[T hostNum](fn func(a T) T, strided bool, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(n int, args []Buffer, consts [][]byte) {
+		offsets := hostOffsets(strided, consts)
+		in, c := elemsOf(args[0], dt), elemsOf(args[1], dt)
+		for i := 0; i < n; i++ {
+			off := offsets(i)
+			c.set(off[maxOperands], fn(in.get(off[0])))
+		}
+	}
+}
+
+// hostTernaryKernel runs fn on elements of first, read by firstOf, and on two operands of elements of dt, read by elemsOf.
+func hostTernaryKernel[A, T hostNum](fn func(a A, b, c T) T, strided bool, firstOf func(Buffer, tensor.Dtype) hostElems[A], first tensor.Dtype, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(n int, args []Buffer, consts [][]byte) {
+		offsets := hostOffsets(strided, consts)
+		a, b, c, r := firstOf(args[0], first), elemsOf(args[1], dt), elemsOf(args[2], dt), elemsOf(args[3], dt)
+		for i := 0; i < n; i++ {
+			off := offsets(i)
+			r.set(off[maxOperands], fn(a.get(off[0]), b.get(off[1]), c.get(off[2])))
+		}
+	}
+}
+
+// hostTernaryScalarKernel is hostTernaryKernel with the scalars in consts as the operands b and c.
+func hostTernaryScalarKernel[T hostNum](fn func(a, b, c T) T, strided bool, elemsOf func(Buffer, tensor.Dtype) hostElems[T], dt tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(n int, args []Buffer, consts [][]byte) {
+		offsets := hostOffsets(strided, consts[2:])
+		in, r := elemsOf(args[0], dt), elemsOf(args[1], dt)
+		scalarB, scalarC := consts[0], consts[1]
+		b := elemsOf(Buffer{ptr: unsafe.Pointer(&scalarB[0]), sz: uintptr(len(scalarB))}, dt).get(0)
+		c := elemsOf(Buffer{ptr: unsafe.Pointer(&scalarC[0]), sz: uintptr(len(scalarC))}, dt).get(0)
+		for i := 0; i < n; i++ {
+			off := offsets(i)
+			r.set(off[maxOperands], fn(in.get(off[0]), b, c))
+		}
+	}
+}
+
+// hostPermuteKernel copies the elements of size bytes of each tile in the same order as permuteKernel does.
+func hostPermuteKernel(size int) func(int, []Buffer, [][]byte) {
+	return func(groups int, args []Buffer, consts [][]byte) {
+		in, result := hostBytes(args[0]), hostBytes(args[1])
+		outer, inner := hostOffsets(true, consts[:1]), layoutOf(consts[1])
+		rows, cols := int(inner.Shape[0]), int(inner.Shape[1])
+		tilesR, tilesC := (rows+permuteTile-1)/permuteTile, (cols+permuteTile-1)/permuteTile
+		for group := 0; group < groups; group++ {
+			base := outer(group / (tilesR * tilesC))
+			r0, c0 := group%(tilesR*tilesC)/tilesC*permuteTile, group%tilesC*permuteTile
+			for r := r0; r < r0+permuteTile && r < rows; r++ {
+				for c := c0; c < c0+permuteTile && c < cols; c++ {
+					src := base[0] + r*int(inner.Strides[0][0]) + c*int(inner.Strides[0][1])
+					dst := base[maxOperands] + r*int(inner.Strides[maxOperands][0]) + c*int(inner.Strides[maxOperands][1])
+					copy(result[dst*size:(dst+1)*size], in[src*size:(src+1)*size])
+				}
+			}
+		}
+	}
+}
+
+// hostReduceKernel reduces in the same order as reduceKernel does, so that both give the same results:
+// each of the reduceThreads partial results is a strided subset of the input, and the partial results are combined pairwise.
+func hostReduceKernel(fn func(a, b float32) float32, identity float32, dt tensor.Dtype) func(int, []Buffer, [][]byte) {
+	return func(groups int, args []Buffer, consts [][]byte) {
+		outer, inner := hostOffsets(true, consts[:1]), hostOffsets(true, consts[1:])
+		in, c := hostElemsOf(args[0], dt), hostElemsOf(args[1], dt)
+		n := layoutSize(consts[1])
+		var partial [reduceThreads]float32
+		for g := 0; g < groups; g++ {
+			base := outer(g)
+			for t := range partial {
+				acc := identity
+				for i := t; i < n; i += reduceThreads {
+					acc = fn(acc, in.get(base[0]+inner(i)[0]))
+				}
+				partial[t] = acc
+			}
+			for s := reduceThreads / 2; s > 0; s >>= 1 {
+				for t := 0; t < s; t++ {
+					partial[t] = fn(partial[t], partial[t+s])
+				}
+			}
+			c.set(base[maxOperands], partial[0])
+		}
+	}
+}
+
+// hostArgKernel finds the best candidates in the same order as argKernel does.
+func hostArgKernel(better func(a, b float32) bool, index, dt tensor.Dtype) func(int, []Buffer, [][]byte) {
+	// isBetter is the better function of argKernel
+	isBetter := func(a float32, i int, b float32, j int) bool {
+		switch {
+		case i == noIndex:
+			return false
+		case j == noIndex:
+			return true
+		case (a != a) != (b != b):
+			return a != a
+		case a != a || a == b:
+			return i < j
+		}
+		return better(a, b)
+	}
+	return func(groups int, args []Buffer, consts [][]byte) {
+		outer, inner := hostOffsets(true, consts[:1]), hostOffsets(true, consts[1:])
+		in := hostElemsOf(args[0], dt)
+		n := layoutSize(consts[1])
+		var values [reduceThreads]float32
+		var indices [reduceThreads]int
+		for g := 0; g < groups; g++ {
+			base := outer(g)
+			for t := range values {
+				values[t], indices[t] = 0, noIndex
+				for i := t; i < n; i += reduceThreads {
+					if v := in.get(base[0] + inner(i)[0]); isBetter(v, i, values[t], indices[t]) {
+						values[t], indices[t] = v, i
+					}
+				}
+			}
+			for s := reduceThreads / 2; s > 0; s >>= 1 {
+				for t := 0; t < s; t++ {
+					if isBetter(values[t+s], indices[t+s], values[t], indices[t]) {
+						values[t], indices[t] = values[t+s], indices[t+s]
+					}
+				}
+			}
+			if index == tensor.Int32 {
+				unsafe.Slice((*int32)(args[1].ptr), args[1].sz/4)[base[maxOperands]] = int32(indices[0])
+			} else {
+				unsafe.Slice((*int64)(args[1].ptr), args[1].sz/8)[base[maxOperands]] = int64(indices[0])
+			}
+		}
+	}
+}
+
+// layoutSize returns the number of elements described by the stridedLayout in b.
+func layoutSize(b []byte) int {
+	l := layoutOf(b)
+	n := 1
+	for d := 0; d < int(l.Dims); d++ {
+		n *= int(l.Shape[d])
+	}
+	return n
+}
+
+func layoutOf(b []byte) (l stridedLayout) {
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&l)), unsafe.Sizeof(l)), b)
+	return l
+}
+
+// hostOffsets returns the offsets of the element at index in each of the operands, and in the result at index maxOperands.
+// For strided kernels, they are given by the stridedLayout in consts, like offsetsOf in MSL.
+func hostOffsets(strided bool, consts [][]byte) func(index int) [maxOperands + 1]int {
+	if !strided {
+		return func(index int) (off [maxOperands + 1]int) {
+			for k := range off {
+				off[k] = index
+			}
+			return off
+		}
+	}
+	l := layoutOf(consts[0])
+	return func(index int) (off [maxOperands + 1]int) {
+		for d := int(l.Dims) - 1; d >= 0; d-- {
+			i := index % int(l.Shape[d])
+			index /= int(l.Shape[d])
+			for k := range off {
+				off[k] += i * int(l.Strides[k][d])
+			}
+		}
+		return off
+	}
+}
+
+// hostNum is the type elements are computed with on the host: float32 for floatDtypes, and int64 for intDtypes and Bools.
+type hostNum interface{ float32 | int64 }
+
+// hostElems reads and writes the elements of a Buffer as Ts, like kernels convert them to and from their compute type.
+type hostElems[T hostNum] struct {
+	get func(i int) T
+	set func(i int, v T)
+}
+
+// hostElemsOf returns the hostElems of a Buffer of the float kernel dtype dt.
+func hostElemsOf(buf Buffer, dt tensor.Dtype) hostElems[float32] {
+	switch dt {
+	case Float16:
+		s := unsafe.Slice((*F16)(buf.ptr), buf.sz/2)
+		return hostElems[float32]{func(i int) float32 { return s[i].Float32() }, func(i int, v float32) { s[i] = NewF16(v) }}
+	case BFloat16:
+		s := unsafe.Slice((*BF16)(buf.ptr), buf.sz/2)
+		return hostElems[float32]{func(i int) float32 { return s[i].Float32() }, func(i int, v float32) { s[i] = NewBF16(v) }}
+	}
+	s := f32s(buf)
+	return hostElems[float32]{func(i int) float32 { return s[i] }, func(i int, v float32) { s[i] = v }}
+}
+
+// hostIntsOf returns the hostElems of a Buffer of one of intDtypes, or of Bools, which are read as 0 or 1 and written as whether they aren't 0.
+// Like in kernels, ints that don't fit the dtype wrap around.
+func hostIntsOf(buf Buffer, dt tensor.Dtype) hostElems[int64] {
+	switch dt {
+	case tensor.Int8:
+		s := unsafe.Slice((*int8)(buf.ptr), buf.sz)
+		return hostElems[int64]{func(i int) int64 { return int64(s[i]) }, func(i int, v int64) { s[i] = int8(v) }}
+	case tensor.Uint8:
+		s := unsafe.Slice((*uint8)(buf.ptr), buf.sz)
+		return hostElems[int64]{func(i int) int64 { return int64(s[i]) }, func(i int, v int64) { s[i] = uint8(v) }}
+	case tensor.Bool:
+		s := unsafe.Slice((*bool)(buf.ptr), buf.sz)
+		get := func(i int) int64 {
+			if s[i] {
+				return 1
+			}
+			return 0
+		}
+		return hostElems[int64]{get, func(i int, v int64) { s[i] = v != 0 }}
+	}
+	s := unsafe.Slice((*int32)(buf.ptr), buf.sz/4)
+	return hostElems[int64]{func(i int) int64 { return int64(s[i]) }, func(i int, v int64) { s[i] = int32(v) }}
+}
+
+func f32s(buf Buffer) []float32 { return unsafe.Slice((*float32)(buf.ptr), buf.sz/4) }
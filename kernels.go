@@ -0,0 +1,725 @@
+package magol
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// The Metal kernel library is generated from the templates in this file, one kernel per op and dtype.
+// Kernels are named after the op, suffixed with the dtype. e.g. "add_f32".
+// The scalar variants of binary ops are suffixed "_vs" when the tensor is the left operand and "_sv" when the scalar is.
+// The strided variants, which index their operands through a stridedLayout, are suffixed "_strided".
+// e.g. "sub_sv_strided_f32".
+// Reductions are prefixed "reduce_", e.g. "reduce_sum_f32".
+// The tiled copies of permuteKernel are named "permute", e.g. "permute_f32".
+// Arg reductions carry the suffix of the dtype of the indices they return before that of their input, e.g. "argmax_i64_f32".
+// Comparisons likewise carry the suffix of the dtype of their result, e.g. "gt_bool_f32" or "gt_f32_f32",
+// and where the suffix of the dtype of its condition, e.g. "where_bool_f32".
+// The variant of clamp with scalar bounds is suffixed "_vs", e.g. "clamp_vs_f32".
+
+// mslType describes how a Dtype is spelled in MSL.
+//
+// Elements are stored as Name, but computed with as Compute: they are converted by Load when read, and by Store when written.
+// Half precision elements are computed with as floats, so that, like on the host, only the results are rounded.
+type mslType struct {
+	Name   string // the MSL type
+	Suffix string // the suffix of the names of the kernels for the Dtype
+
+	Compute string // the MSL type elements are computed with
+	load    string // the MSL function converting a Name into a Compute, if they differ
+	store   string // the MSL function converting a Compute into a Name, if they differ
+}
+
+// Load returns the MSL expression converting the element expr into a Compute.
+func (t mslType) Load(expr string) string { return convert(t.load, expr) }
+
+// Store returns the MSL expression converting the Compute expr into an element.
+func (t mslType) Store(expr string) string { return convert(t.store, expr) }
+
+func convert(fn, expr string) string {
+	if fn == "" {
+		return expr
+	}
+	return fn + "(" + expr + ")"
+}
+
+// floatDtypes and intDtypes are the kernel dtypes computed with as floats and as ints.
+var (
+	floatDtypes = []tensor.Dtype{tensor.Float32, Float16, BFloat16}
+	intDtypes   = []tensor.Dtype{tensor.Int32, tensor.Int8, tensor.Uint8}
+)
+
+// kernelDtypes are the dtypes kernels are generated for. Bools only have copies and comparisons for equality.
+var kernelDtypes = append(append(append([]tensor.Dtype(nil), floatDtypes...), intDtypes...), tensor.Bool)
+
+// indexDtypes are the dtypes of the indices returned by arg reductions.
+var indexDtypes = []tensor.Dtype{tensor.Int, tensor.Int32}
+
+// mslTypes spell the Dtypes in MSL. BFloat16s are stored as ushorts, as the bfloat type of MSL needs Metal 3.1.
+// Int8s and Uint8s are computed with as ints, as MSL promotes them anyway, and wrap around when stored.
+var mslTypes = map[tensor.Dtype]mslType{
+	tensor.Float32: {"float", "f32", "float", "", ""},
+	Float16:        {"half", "f16", "float", "float", "half"},
+	BFloat16:       {"ushort", "bf16", "float", "bf16_to_float", "float_to_bf16"},
+	tensor.Int:     {"long", "i64", "long", "", ""},
+	tensor.Int32:   {"int", "i32", "int", "", ""},
+	tensor.Int8:    {"char", "i8", "int", "", "char"},
+	tensor.Uint8:   {"uchar", "u8", "int", "", "uchar"},
+	tensor.Bool:    {"bool", "bool", "bool", "", ""},
+}
+
+// hasKernels reports whether kernels are generated for dt.
+func hasKernels(dt tensor.Dtype) bool { return containsDtype(kernelDtypes, dt) }
+
+// isInt reports whether dt is one of intDtypes.
+func isInt(dt tensor.Dtype) bool { return containsDtype(intDtypes, dt) }
+
+func containsDtype(dts []tensor.Dtype, dt tensor.Dtype) bool {
+	for _, d := range dts {
+		if d == dt {
+			return true
+		}
+	}
+	return false
+}
+
+// binOp is an elementwise binary op.
+type binOp struct {
+	Name    string // the name of the kernel, without the dtype suffix
+	Expr    string // the MSL expression of the result in terms of a and b
+	IntExpr string // the MSL expression of the result for intDtypes, if the op has kernels for them
+}
+
+// exprOf returns the expression of op for dt, or "" if op has no kernel for it.
+func (op binOp) exprOf(dt tensor.Dtype) string {
+	switch {
+	case dt == tensor.Bool:
+		return ""
+	case isInt(dt):
+		return op.IntExpr
+	}
+	return op.Expr
+}
+
+// binOps are the elementwise binary ops. Integer divisions by zero give 0, as they do on Apple GPUs.
+var binOps = []binOp{
+	{"add", "a + b", "a + b"},
+	{"sub", "a - b", "a - b"},
+	{"mul", "a * b", "a * b"},
+	{"div", "a / b", "b == 0 ? 0 : a / b"},
+	{"pow", "pow(a, b)", ""},
+	{"mod", "fmod(a, b)", "b == 0 ? 0 : a % b"},
+	{"min", "min(a, b)", "min(a, b)"},
+	{"max", "max(a, b)", "max(a, b)"},
+}
+
+// unaryOp is an elementwise unary op.
+type unaryOp struct {
+	Name    string // the name of the kernel, without the dtype suffix
+	Expr    string // the MSL expression of the result in terms of a
+	IntExpr string // the MSL expression of the result for intDtypes, if the op has kernels for them
+}
+
+// exprOf returns the expression of op for dt, or "" if op has no kernel for it.
+func (op unaryOp) exprOf(dt tensor.Dtype) string {
+	if op == copyOp {
+		return op.Expr
+	}
+	return binOp(op).exprOf(dt)
+}
+
+// copyOp copies elements. Unlike unaryOps, it has kernels for every dtype.
+var copyOp = unaryOp{"copy", "a", "a"}
+
+var unaryOps = []unaryOp{
+	{"exp", "exp(a)", ""},
+	{"log", "log(a)", ""},
+	{"sqrt", "sqrt(a)", ""},
+	{"rsqrt", "rsqrt(a)", ""},
+	{"tanh", "tanh(a)", ""},
+	{"sigmoid", "1 / (1 + exp(-a))", ""},
+	{"abs", "abs(a)", "abs(a)"},
+	{"neg", "-a", "-a"},
+	{"sign", "sign(a)", "(a > 0) - (a < 0)"},
+	{"square", "a * a", "a * a"},
+	{"cube", "a * a * a", "a * a * a"},
+}
+
+// cmpOp is an elementwise comparison.
+// Its kernels return either Bools, or elements of the dtype of the operands: 1 for true and 0 for false.
+type cmpOp struct {
+	Name  string // the name of the kernel, without the dtype suffixes
+	Expr  string // the MSL expression of the comparison of a and b
+	Bools bool   // whether Bools can be compared, which only makes sense for equality
+}
+
+var cmpOps = []cmpOp{
+	{"gt", "a > b", false},
+	{"gte", "a >= b", false},
+	{"lt", "a < b", false},
+	{"lte", "a <= b", false},
+	{"eq", "a == b", true},
+	{"ne", "a != b", true},
+}
+
+// cmpResults returns the dtypes of the results of comparisons of elements of dt.
+func cmpResults(dt tensor.Dtype) []tensor.Dtype {
+	if dt == tensor.Bool {
+		return []tensor.Dtype{tensor.Bool}
+	}
+	return []tensor.Dtype{tensor.Bool, dt}
+}
+
+// ternaryOp is an elementwise op of three operands.
+type ternaryOp struct {
+	Name    string // the name of the kernel, without the dtype suffixes
+	Expr    string // the MSL expression of the result in terms of a, b and c
+	IntExpr string // the MSL expression of the result for intDtypes, if the op has kernels for them
+}
+
+// exprOf returns the expression of op for dt, or "" if op has no kernel for it.
+func (op ternaryOp) exprOf(dt tensor.Dtype) string {
+	if op == whereOp {
+		return op.Expr
+	}
+	return binOp(op).exprOf(dt)
+}
+
+// whereOp selects b where the condition a is true, or isn't 0, and c elsewhere. Like copyOp, it has kernels for every dtype.
+var whereOp = ternaryOp{"where", "a ? b : c", "a ? b : c"}
+
+// clampOp clamps a between b and c. Unlike MSL's clamp, it keeps NaNs, as tensor.StdEng does.
+var clampOp = ternaryOp{"clamp", "a < b ? b : (a > c ? c : a)", "a < b ? b : (a > c ? c : a)"}
+
+// whereConds returns the dtypes of the conditions of where, selecting elements of dt: Bools, and dt itself.
+func whereConds(dt tensor.Dtype) []tensor.Dtype { return cmpResults(dt) }
+
+// reduceOp is a reduction.
+type reduceOp struct {
+	Name     string // the name of the reduction, without the "reduce_" prefix and the dtype suffix
+	Expr     string // the MSL expression combining the partial results a and b
+	Identity string // the MSL expression of the identity of Expr
+}
+
+var reduceOps = []reduceOp{
+	{"sum", "a + b", "0"},
+	{"prod", "a * b", "1"},
+	{"max", "max(a, b)", "-INFINITY"},
+	{"min", "min(a, b)", "INFINITY"},
+}
+
+// argOps are the arg reductions. Expr tells whether a is a better candidate than b, when neither is a NaN.
+// Like reduceOps, they only have kernels for floatDtypes.
+var argOps = []binOp{
+	{"argmax", "a > b", ""},
+	{"argmin", "a < b", ""},
+}
+
+// reduceThreads is the number of threads each output element of a reduction is reduced by.
+// It must be a power of two, and match REDUCE_THREADS in libraryHeader.
+const reduceThreads = 256
+
+// permuteTile is the side of the square tiles permuteKernel copies, one per threadgroup of reduceThreads threads.
+// Its square must be reduceThreads, and it must match PERMUTE_TILE in libraryHeader.
+const permuteTile = 16
+
+// maxDims is the maximum number of dimensions of the tensors strided kernels work on.
+const maxDims = 8
+
+// maxOperands is the maximum number of operands of elementwise kernels.
+const maxOperands = 3
+
+// stridedLayout is passed to the strided kernels as a constant argument. It matches StridedLayout in libraryHeader.
+type stridedLayout struct {
+	Dims  uint32
+	Shape [maxDims]uint32
+	// Strides holds the strides, in elements, of the operands and the result.
+	// The result always comes last, at index maxOperands. A stride of 0 broadcasts the operand along the dimension.
+	Strides [maxOperands + 1][maxDims]uint32
+}
+
+const libraryHeader = `#include <metal_stdlib>
+using namespace metal;
+
+#define MAX_DIMS 8
+#define MAX_OPERANDS 3
+#define REDUCE_THREADS 256
+#define PERMUTE_TILE 16
+#define NO_INDEX UINT_MAX
+
+struct StridedLayout {
+    uint dims;
+    uint shape[MAX_DIMS];
+    uint strides[MAX_OPERANDS + 1][MAX_DIMS];
+};
+
+// bf16_to_float converts the bits of a bfloat16 to a float.
+static float bf16_to_float(ushort x) {
+    return as_type<float>(uint(x) << 16);
+}
+
+// float_to_bf16 rounds a float to the bits of the nearest bfloat16, with ties to even. NaNs stay NaNs.
+static ushort float_to_bf16(float f) {
+    uint b = as_type<uint>(f);
+    if (isnan(f)) {
+        return ushort((b >> 16) | 0x40);
+    }
+    return ushort((b + 0x7fff + ((b >> 16) & 1)) >> 16);
+}
+
+// offsetsOf returns the offsets of the element at index in each of the operands, and in the result as w.
+static uint4 offsetsOf(constant StridedLayout& l, uint index) {
+    uint4 off = 0;
+    for (uint d = l.dims; d > 0; d--) {
+        uint i = index % l.shape[d-1];
+        index /= l.shape[d-1];
+        off += i * uint4(l.strides[0][d-1], l.strides[1][d-1], l.strides[2][d-1], l.strides[3][d-1]);
+    }
+    return off;
+}
+`
+
+// kernel is the data the templates are executed with.
+type kernel struct {
+	Name    string // the full name of the kernel
+	Expr    string
+	T       mslType
+	Strided bool
+	Left    bool // for scalar kernels, whether the tensor is the left operand
+
+	Identity string  // for reductions, the identity of Expr
+	Result   mslType // the type of the result, if it isn't T: the indices of arg reductions and the results of comparisons
+	First    mslType // the type of the first operand, if it isn't T: the conditions of where
+}
+
+// R returns the type of the result of the kernel.
+func (k kernel) R() mslType {
+	if k.Result.Name == "" {
+		return k.T
+	}
+	return k.Result
+}
+
+// A returns the type of the first operand of the kernel.
+func (k kernel) A() mslType {
+	if k.First.Name == "" {
+		return k.T
+	}
+	return k.First
+}
+
+const offsets = `
+{{- if .Strided}}
+    uint4 off = offsetsOf(l, index);
+{{- else}}
+    uint4 off = index;
+{{- end}}`
+
+const layoutArg = `
+{{- if .Strided}}
+    constant StridedLayout& l,
+{{- end}}`
+
+var binKernel = template.Must(template.New("binKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* inA,
+    device const {{.T.Name}}* inB,
+    device {{.R.Name}}* result,` + layoutArg + `
+    uint index [[thread_position_in_grid]])
+{` + offsets + `
+    {{.T.Compute}} a = {{.T.Load "inA[off.x]"}};
+    {{.T.Compute}} b = {{.T.Load "inB[off.y]"}};
+    result[off.w] = {{.R.Store .Expr}};
+}
+`))
+
+var scalarKernel = template.Must(template.New("scalarKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.R.Name}}* result,
+    constant {{.T.Name}}& scalar,` + layoutArg + `
+    uint index [[thread_position_in_grid]])
+{` + offsets + `
+{{- if .Left}}
+    {{.T.Compute}} a = {{.T.Load "in[off.x]"}};
+    {{.T.Compute}} b = {{.T.Load "scalar"}};
+{{- else}}
+    {{.T.Compute}} a = {{.T.Load "scalar"}};
+    {{.T.Compute}} b = {{.T.Load "in[off.x]"}};
+{{- end}}
+    result[off.w] = {{.R.Store .Expr}};
+}
+`))
+
+var unaryKernel = template.Must(template.New("unaryKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.T.Name}}* result,` + layoutArg + `
+    uint index [[thread_position_in_grid]])
+{` + offsets + `
+    {{.T.Compute}} a = {{.T.Load "in[off.x]"}};
+    result[off.w] = {{.T.Store .Expr}};
+}
+`))
+
+var ternaryKernel = template.Must(template.New("ternaryKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.A.Name}}* inA,
+    device const {{.T.Name}}* inB,
+    device const {{.T.Name}}* inC,
+    device {{.T.Name}}* result,` + layoutArg + `
+    uint index [[thread_position_in_grid]])
+{` + offsets + `
+    {{.A.Compute}} a = {{.A.Load "inA[off.x]"}};
+    {{.T.Compute}} b = {{.T.Load "inB[off.y]"}};
+    {{.T.Compute}} c = {{.T.Load "inC[off.z]"}};
+    result[off.w] = {{.T.Store .Expr}};
+}
+`))
+
+// ternaryScalarKernel is ternaryKernel with scalars as the operands b and c.
+var ternaryScalarKernel = template.Must(template.New("ternaryScalarKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.T.Name}}* result,
+    constant {{.T.Name}}& scalarB,
+    constant {{.T.Name}}& scalarC,` + layoutArg + `
+    uint index [[thread_position_in_grid]])
+{` + offsets + `
+    {{.T.Compute}} a = {{.T.Load "in[off.x]"}};
+    {{.T.Compute}} b = {{.T.Load "scalarB"}};
+    {{.T.Compute}} c = {{.T.Load "scalarC"}};
+    result[off.w] = {{.T.Store .Expr}};
+}
+`))
+
+// reduceKernel reduces the input over the dimensions described by inner, for each element of the result described by outer.
+// Each output element is reduced by one threadgroup: every thread first reduces a strided subset of the input,
+// then the partial results are combined pairwise, halving the number of active threads each step.
+var reduceKernel = template.Must(template.New("reduceKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.T.Name}}* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    threadgroup {{.T.Compute}} partial[REDUCE_THREADS];
+    uint4 base = offsetsOf(outer, group);
+    uint n = 1;
+    for (uint d = 0; d < inner.dims; d++) {
+        n *= inner.shape[d];
+    }
+    {{.T.Compute}} acc = {{.Identity}};
+    for (uint i = tid; i < n; i += REDUCE_THREADS) {
+        {{.T.Compute}} a = acc;
+        {{.T.Compute}} b = {{.T.Load "in[base.x + offsetsOf(inner, i).x]"}};
+        acc = {{.Expr}};
+    }
+    partial[tid] = acc;
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    for (uint s = REDUCE_THREADS / 2; s > 0; s >>= 1) {
+        if (tid < s) {
+            {{.T.Compute}} a = partial[tid];
+            {{.T.Compute}} b = partial[tid + s];
+            partial[tid] = {{.Expr}};
+        }
+        threadgroup_barrier(mem_flags::mem_threadgroup);
+    }
+    if (tid == 0) {
+        result[base.w] = {{.T.Store "partial[0]"}};
+    }
+}
+`))
+
+// argKernel finds the index of the best element along the dimensions described by inner, for each element of the result.
+// NaNs are better than any other value, and ties go to the lowest index.
+// Like reduceKernel, each output element is reduced by one threadgroup, with the candidates of the threads combined pairwise.
+var argKernel = template.Must(template.New("argKernel").Parse(`
+// {{.Name}}_better reports whether a, at index i, is a better candidate than b, at index j.
+static bool {{.Name}}_better({{.T.Compute}} a, uint i, {{.T.Compute}} b, uint j) {
+    if (i == NO_INDEX) {
+        return false;
+    }
+    if (j == NO_INDEX) {
+        return true;
+    }
+    if (isnan(a) != isnan(b)) {
+        return isnan(a);
+    }
+    if (isnan(a) || a == b) {
+        return i < j;
+    }
+    return {{.Expr}};
+}
+
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.R.Name}}* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    threadgroup {{.T.Compute}} values[REDUCE_THREADS];
+    threadgroup uint indices[REDUCE_THREADS];
+    uint4 base = offsetsOf(outer, group);
+    uint n = 1;
+    for (uint d = 0; d < inner.dims; d++) {
+        n *= inner.shape[d];
+    }
+    {{.T.Compute}} best = 0;
+    uint at = NO_INDEX;
+    for (uint i = tid; i < n; i += REDUCE_THREADS) {
+        {{.T.Compute}} v = {{.T.Load "in[base.x + offsetsOf(inner, i).x]"}};
+        if ({{.Name}}_better(v, i, best, at)) {
+            best = v;
+            at = i;
+        }
+    }
+    values[tid] = best;
+    indices[tid] = at;
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    for (uint s = REDUCE_THREADS / 2; s > 0; s >>= 1) {
+        if (tid < s && {{.Name}}_better(values[tid + s], indices[tid + s], values[tid], indices[tid])) {
+            values[tid] = values[tid + s];
+            indices[tid] = indices[tid + s];
+        }
+        threadgroup_barrier(mem_flags::mem_threadgroup);
+    }
+    if (tid == 0) {
+        result[base.w] = indices[0] == NO_INDEX ? -1 : {{.R.Name}}(indices[0]);
+    }
+}
+`))
+
+// permuteKernel copies the input into the result, which are laid out along different axes, a tile at a time.
+// The tiles span the two dimensions described by inner: the input is contiguous along the first, and the result along the second.
+// Each tile is read into threadgroup memory along the first, and written out of it along the second,
+// so that both the reads and the writes of consecutive threads are to consecutive elements.
+// outer maps the threadgroups to the other dimensions, over which the tiles of each are laid out in order.
+var permuteKernel = template.Must(template.New("permuteKernel").Parse(`
+kernel void {{.Name}}(
+    device const {{.T.Name}}* in,
+    device {{.T.Name}}* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    // the padding column keeps the threads reading a column of the tile out of each other's banks
+    threadgroup {{.T.Name}} tile[PERMUTE_TILE][PERMUTE_TILE + 1];
+    uint rows = inner.shape[0];
+    uint cols = inner.shape[1];
+    uint tilesR = (rows + PERMUTE_TILE - 1) / PERMUTE_TILE;
+    uint tilesC = (cols + PERMUTE_TILE - 1) / PERMUTE_TILE;
+    uint4 base = offsetsOf(outer, group / (tilesR * tilesC));
+    uint r0 = group % (tilesR * tilesC) / tilesC * PERMUTE_TILE;
+    uint c0 = group % tilesC * PERMUTE_TILE;
+    uint tx = tid % PERMUTE_TILE;
+    uint ty = tid / PERMUTE_TILE;
+    uint r = r0 + tx;
+    uint c = c0 + ty;
+    if (r < rows && c < cols) {
+        tile[ty][tx] = in[base.x + r * inner.strides[0][0] + c * inner.strides[0][1]];
+    }
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    r = r0 + ty;
+    c = c0 + tx;
+    if (r < rows && c < cols) {
+        result[base.w + r * inner.strides[MAX_OPERANDS][0] + c * inner.strides[MAX_OPERANDS][1]] = tile[tx][ty];
+    }
+}
+`))
+
+// kernelName returns the name of the kernel of op for the given dtype.
+func kernelName(op string, dt tensor.Dtype) (string, error) {
+	t, ok := mslTypes[dt]
+	if !ok {
+		return "", errors.Errorf("No kernels for %v", dt)
+	}
+	return op + "_" + t.Suffix, nil
+}
+
+// stridedName returns the name of the kernel of op for the given dtype, or of its strided variant.
+func stridedName(op string, strided bool, dt tensor.Dtype) (string, error) {
+	if strided {
+		op += "_strided"
+	}
+	return kernelName(op, dt)
+}
+
+// scalarKernelName returns the name of the kernel of the scalar variant of op for the given dtype.
+func scalarKernelName(op string, leftTensor, strided bool, dt tensor.Dtype) (string, error) {
+	if leftTensor {
+		return stridedName(op+"_vs", strided, dt)
+	}
+	return stridedName(op+"_sv", strided, dt)
+}
+
+// reduceName returns the name of the kernel of the reduction op for the given dtype.
+func reduceName(op string, dt tensor.Dtype) (string, error) {
+	return kernelName("reduce_"+op, dt)
+}
+
+// cmpName returns the name of the kernel of the comparison op for the given dtype, returning elements of the dtype result.
+// For scalar comparisons, leftTensor tells whether the tensor is the left operand.
+func cmpName(op string, scalar, leftTensor, strided bool, result, dt tensor.Dtype) (string, error) {
+	r, ok := mslTypes[result]
+	if !ok {
+		return "", errors.Errorf("No kernels for results of %v", result)
+	}
+	switch {
+	case scalar && leftTensor:
+		op += "_vs"
+	case scalar:
+		op += "_sv"
+	}
+	if strided {
+		op += "_strided"
+	}
+	return kernelName(op+"_"+r.Suffix, dt)
+}
+
+// permuteName returns the name of the kernel of the tiled copies of the given dtype.
+func permuteName(dt tensor.Dtype) (string, error) {
+	return kernelName("permute", dt)
+}
+
+// whereName returns the name of the kernel of where for the given dtype, with conditions of the dtype cond.
+func whereName(strided bool, cond, dt tensor.Dtype) (string, error) {
+	c, ok := mslTypes[cond]
+	if !ok {
+		return "", errors.Errorf("No kernels for conditions of %v", cond)
+	}
+	op := whereOp.Name
+	if strided {
+		op += "_strided"
+	}
+	return kernelName(op+"_"+c.Suffix, dt)
+}
+
+// argName returns the name of the kernel of the arg reduction op for the given dtype, returning indices of the dtype index.
+func argName(op string, index, dt tensor.Dtype) (string, error) {
+	t, ok := mslTypes[index]
+	if !ok {
+		return "", errors.Errorf("No kernels for indices of %v", index)
+	}
+	return kernelName(op+"_"+t.Suffix, dt)
+}
+
+// kernelSource is the source of a kernel yet to be generated.
+type kernelSource struct {
+	tmpl *template.Template
+	k    kernel
+}
+
+func (s kernelSource) gen() (string, error) {
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, s.k); err != nil {
+		return "", errors.Wrapf(err, "Unable to generate %q", s.k.Name)
+	}
+	return buf.String(), nil
+}
+
+// kernelSources returns the sources of all the kernels in the library.
+func kernelSources() []kernelSource {
+	var retVal []kernelSource
+	for _, dt := range kernelDtypes {
+		t := mslTypes[dt]
+		for _, strided := range []bool{false, true} {
+			for _, op := range binOps {
+				expr := op.exprOf(dt)
+				if expr == "" {
+					continue
+				}
+				name, _ := stridedName(op.Name, strided, dt)
+				retVal = append(retVal, kernelSource{binKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided}})
+				for _, left := range []bool{true, false} {
+					name, _ = scalarKernelName(op.Name, left, strided, dt)
+					retVal = append(retVal, kernelSource{scalarKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided, Left: left}})
+				}
+			}
+			for _, op := range append([]unaryOp{copyOp}, unaryOps...) {
+				expr := op.exprOf(dt)
+				if expr == "" {
+					continue
+				}
+				name, _ := stridedName(op.Name, strided, dt)
+				retVal = append(retVal, kernelSource{unaryKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided}})
+			}
+			for _, op := range cmpOps {
+				if dt == tensor.Bool && !op.Bools {
+					continue
+				}
+				for _, result := range cmpResults(dt) {
+					r := mslTypes[result]
+					expr := op.Expr
+					if result != tensor.Bool {
+						expr = r.Compute + "(" + expr + ")"
+					}
+					name, _ := cmpName(op.Name, false, false, strided, result, dt)
+					retVal = append(retVal, kernelSource{binKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided, Result: r}})
+					for _, left := range []bool{true, false} {
+						name, _ = cmpName(op.Name, true, left, strided, result, dt)
+						retVal = append(retVal, kernelSource{scalarKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided, Left: left, Result: r}})
+					}
+				}
+			}
+			for _, cond := range whereConds(dt) {
+				name, _ := whereName(strided, cond, dt)
+				retVal = append(retVal, kernelSource{ternaryKernel, kernel{Name: name, Expr: whereOp.Expr, T: t, Strided: strided, First: mslTypes[cond]}})
+			}
+			if expr := clampOp.exprOf(dt); expr != "" {
+				name, _ := stridedName(clampOp.Name, strided, dt)
+				retVal = append(retVal, kernelSource{ternaryKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided}})
+				name, _ = stridedName(clampOp.Name+"_vs", strided, dt)
+				retVal = append(retVal, kernelSource{ternaryScalarKernel, kernel{Name: name, Expr: expr, T: t, Strided: strided}})
+			}
+		}
+		name, _ := permuteName(dt)
+		retVal = append(retVal, kernelSource{permuteKernel, kernel{Name: name, T: t}})
+		if !containsDtype(floatDtypes, dt) {
+			continue
+		}
+		for _, op := range reduceOps {
+			name, _ := reduceName(op.Name, dt)
+			retVal = append(retVal, kernelSource{reduceKernel, kernel{Name: name, Expr: op.Expr, T: t, Identity: op.Identity}})
+		}
+		for _, op := range argOps {
+			for _, index := range indexDtypes {
+				name, _ := argName(op.Name, index, dt)
+				retVal = append(retVal, kernelSource{argKernel, kernel{Name: name, Expr: op.Expr, T: t, Result: mslTypes[index]}})
+			}
+		}
+	}
+	return retVal
+}
+
+// kernelNames is the set of the names of all the kernels of the library, which ops check their kernels against before allocating their results.
+var kernelNames = func() map[string]bool {
+	retVal := make(map[string]bool)
+	for _, s := range kernelSources() {
+		retVal[s.k.Name] = true
+	}
+	return retVal
+}()
+
+// genLibrary generates the MSL of the whole kernel library, along with the names of all its kernels.
+func genLibrary() (src string, names []string, err error) {
+	var buf bytes.Buffer
+	buf.WriteString(libraryHeader)
+	for _, s := range kernelSources() {
+		k, err := s.gen()
+		if err != nil {
+			return "", nil, err
+		}
+		buf.WriteString(k)
+		names = append(names, s.k.Name)
+	}
+	return buf.String(), names, nil
+}
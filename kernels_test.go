@@ -0,0 +1,79 @@
+package magol
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the generated kernels")
+
+func TestKernelSources(t *testing.T) {
+	for _, s := range kernelSources() {
+		t.Run(s.k.Name, func(t *testing.T) {
+			src, err := s.gen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "kernels", s.k.Name+".metal")
+			if *update {
+				if err := os.WriteFile(golden, []byte(src), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(want), src)
+		})
+	}
+}
+
+func TestGenLibrary(t *testing.T) {
+	src, names, err := genLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, src, libraryHeader)
+	assert.Contains(t, src, fmt.Sprintf("#define MAX_DIMS %d", maxDims))
+	assert.Contains(t, src, fmt.Sprintf("#define MAX_OPERANDS %d", maxOperands))
+	assert.Contains(t, src, fmt.Sprintf("#define REDUCE_THREADS %d", reduceThreads))
+	assert.Contains(t, src, fmt.Sprintf("#define PERMUTE_TILE %d", permuteTile))
+	assert.Equal(t, reduceThreads, permuteTile*permuteTile, "a threadgroup should copy a tile")
+	n := len(floatDtypes)*(len(reduceOps)+len(argOps)*len(indexDtypes)) + len(kernelDtypes)
+	for _, dt := range kernelDtypes {
+		for _, op := range binOps {
+			if op.exprOf(dt) != "" {
+				n += 3 * 2
+			}
+		}
+		for _, op := range append([]unaryOp{copyOp}, unaryOps...) {
+			if op.exprOf(dt) != "" {
+				n += 2
+			}
+		}
+		for _, op := range cmpOps {
+			if dt != tensor.Bool || op.Bools {
+				n += 3 * 2 * len(cmpResults(dt))
+			}
+		}
+		n += 2 * len(whereConds(dt))
+		if clampOp.exprOf(dt) != "" {
+			n += 2 * 2
+		}
+	}
+	assert.Len(t, names, n)
+	for _, name := range names {
+		assert.Contains(t, src, "kernel void "+name+"(")
+		assert.Contains(t, hostKernels, name, "every kernel should have a host reference")
+	}
+
+	_, err = kernelName("add", tensor.Complex128)
+	assert.Error(t, err)
+}
@@ -1,3 +1,6 @@
+//go:build darwin
+// +build darwin
+
 package magol
 
 /*
@@ -25,15 +28,26 @@ type MatrixDesc struct {
 func (d MatrixDesc) isDesc() {}
 
 func desc2MDesc(t tensor.DenseTensor) (MatrixDesc, error) {
-	desc := t.Info()
-	if desc.Dims() != 2 {
-		return MatrixDesc{}, errors.New("Expected Matrix")
+	l, trans, err := matrixLayout(t)
+	if err != nil {
+		return MatrixDesc{}, err
+	}
+	if trans {
+		return MatrixDesc{}, errors.New("Expected a row major matrix")
+	}
+	return layout2MDesc(l)
+}
+
+func layout2MDesc(l MatrixLayout) (MatrixDesc, error) {
+	matrixBytes := l.Rows * l.RowBytes
+	if l.batches() > 1 {
+		matrixBytes = l.MatrixBytes
+	}
+	dataType, err := mpsDataType(l.Dtype)
+	if err != nil {
+		return MatrixDesc{}, err
 	}
-	shp := desc.Shape()
-	rows := shp[0]
-	cols := shp[1]
-	rowBytes := int(t.Dtype().Size()) * cols
-	mdesc := C.MatrixDesc(C.uint_t(rows), C.uint_t(cols), C.uint_t(rowBytes)) // TODO: type mapping
+	mdesc := C.MatrixDesc(C.uint_t(l.Rows), C.uint_t(l.Cols), C.uint_t(l.batches()), C.uint_t(l.RowBytes), C.uint_t(matrixBytes), C.uint_t(dataType))
 	if mdesc == nil {
 		return MatrixDesc{}, errors.New("Failed to create Matrix Descriptor")
 	}
@@ -50,7 +64,7 @@ type Matrix struct {
 }
 
 func NewMatrix(buf Buffer, desc MatrixDesc) *Matrix {
-	return &Matrix{m: C.Matrix(buf.b, desc.d), b: buf}
+	return &Matrix{m: C.Matrix(buf.b, C.size_t(buf.off), desc.d), b: buf}
 }
 
 func (m *Matrix) Buffer() Buffer { return m.b }
@@ -67,27 +81,25 @@ type VectorDescriptor struct {
 func (d VectorDescriptor) isDesc() {}
 
 func desc2VDesc(t tensor.DenseTensor) (VectorDescriptor, error) {
-	desc := t.Info()
-	if !desc.IsVectorLike() {
-		return VectorDescriptor{}, errors.New("Expected a vectorlike matrix")
+	l, err := vectorLayout(t)
+	if err != nil {
+		return VectorDescriptor{}, err
 	}
-	length := getVecLen(desc.Shape())
-	vdesc := C.VectorDesc(C.uint_t(length))
+	return layout2VDesc(l)
+}
+
+func layout2VDesc(l VectorLayout) (VectorDescriptor, error) {
+	dataType, err := mpsDataType(l.Dtype)
+	if err != nil {
+		return VectorDescriptor{}, err
+	}
+	vdesc := C.VectorDesc(C.uint_t(l.Length), C.uint_t(dataType))
 	if vdesc == nil {
 		return VectorDescriptor{}, errors.New("Failed to create Vector Descriptor")
 	}
 	return VectorDescriptor{vdesc}, nil
 }
 
-func getVecLen(s tensor.Shape) int {
-	for _, d := range s {
-		if d != 1 {
-			return d
-		}
-	}
-	return 1
-}
-
 // Vector represents a vector.
 // See: https://developer.apple.com/documentation/metalperformanceshaders/mpsvector?language=objc
 type Vector struct {
@@ -96,11 +108,12 @@ type Vector struct {
 }
 
 func NewVector(buf Buffer, desc VectorDescriptor) *Vector {
-	return &Vector{v: C.Vector(buf.b, desc.d), b: buf}
+	return &Vector{v: C.Vector(buf.b, C.size_t(buf.off), desc.d), b: buf}
 }
 
-func MPSMatMul(cmdBuf CommandBuffer, A, B, CM *Matrix) error {
-	C.matmul(cmdBuf.b, A.m, B.m, CM.m, C.bool(A.trans), C.bool(B.trans))
+// MPSMatMul encodes CM = alpha·op(A)·op(B) + beta·CM, where op transposes the matrices that are toggled as transposed.
+func MPSMatMul(cmdBuf CommandBuffer, A, B, CM *Matrix, alpha, beta float32) error {
+	C.matmul(cmdBuf.b, A.m, B.m, CM.m, C.bool(A.trans), C.bool(B.trans), C.double(alpha), C.double(beta))
 	return nil
 }
 
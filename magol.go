@@ -22,38 +22,36 @@ type Dataer interface {
 	Float32s() []float32
 }
 
-// Buffer is a memory slice in the GPU.
+// Free releases the MTLBuffer.
 //
 // See: https://developer.apple.com/documentation/metal/mtlbuffer?language=objc
-type Buffer struct {
-	b  unsafe.Pointer
-	sz uintptr
-}
-
-func (b Buffer) Uintptr() uintptr { return uintptr(b.b) }
-func (b Buffer) MemSize() uintptr { return b.sz }
-func (b Buffer) Free()            { C.FreeMBuf(b.b); b.b = nil }
+func (b Buffer) Free() { C.FreeMBuf(b.b); b.b = nil }
 
-func AllocMBuf(device *Device, sz int64) Buffer {
-	return Buffer{b: C.AllocMBuf(device.d, C.size_t(sz)), sz: uintptr(sz)}
+// mBuf wraps an MTLBuffer. The buffer is expected to be in shared storage mode, so its contents are accessible from Go.
+func mBuf(b unsafe.Pointer, sz uintptr) Buffer {
+	if b == nil {
+		return Buffer{}
+	}
+	return Buffer{b: b, ptr: C.MBufContents(b), sz: sz}
 }
 
-func memAsMBuf(a tensor.Memory) Buffer {
-	return Buffer{b: unsafe.Pointer(a.Uintptr()), sz: a.MemSize()}
+func AllocMBuf(device *Device, sz int64) Buffer {
+	return mBuf(C.AllocMBuf(device.d, C.size_t(sz)), uintptr(sz))
 }
 
 func buf2MBuf(device *Device, data tensor.Memory) Buffer {
-	bytes := unsafe.Pointer(data.Uintptr())
+	bytes := ptrOf(data.Uintptr())
 	len := int(data.MemSize())
-	return Buffer{b: C.Buf2MBuf(device.d, bytes, C.size_t(len)), sz: data.MemSize()}
+	return mBuf(C.Buf2MBuf(device.d, bytes, C.size_t(len)), data.MemSize())
 }
 
 func Mbuf2Buf(dst tensor.Memory, src tensor.Memory) {
 	switch src := src.(type) {
 	case Buffer:
-		C.MBuf2Buf(unsafe.Pointer(dst.Uintptr()), src.b, C.size_t(dst.MemSize()))
+		C.MBuf2Buf(ptrOf(dst.Uintptr()), src.b, C.size_t(dst.MemSize()))
 	default:
-		C.MBuf2Buf(unsafe.Pointer(dst.Uintptr()), unsafe.Pointer(src.Uintptr()), C.size_t(dst.MemSize()))
+		// tensors carry the contents address of their Buffer
+		copy(hostMem(dst), hostMem(src))
 	}
 
 }
@@ -62,7 +60,7 @@ func GoSliceAsMBuf[T any](d *Device, s []T) Buffer {
 	ptr := unsafe.Pointer(&s[0])
 	var v T
 	l := uintptr(len(s)) * unsafe.Sizeof(v)
-	return Buffer{b: C.Buf2MBuf(d.d, ptr, C.size_t(l)), sz: l}
+	return mBuf(C.Buf2MBuf(d.d, ptr, C.size_t(l)), l)
 }
 
 func debug(m *Matrix) {
@@ -1,5 +1,8 @@
 // +build darwin
 
+#ifndef MAGOL_H
+#define MAGOL_H
+
 typedef unsigned long uint_t;
 typedef unsigned char uint8_t;
 typedef unsigned short uint16_t;
@@ -21,14 +24,18 @@ void* MakeCommandBuffer(void* cmdq);
 void* Buf2MBuf(void* device, const void* bytes, size_t memsize);
 void* AllocMBuf(void* device, size_t memsize);
 void* FreeMBuf(void* mBuf);
-void* MatrixDesc(uint_t rows, uint_t cols, uint_t rowBytes);
-void* Matrix(void* buf, void* desc);
-void* VectorDesc(uint_t length);
-void* Vector(void* buf, void* desc);
+void* MatrixDesc(uint_t rows, uint_t cols, uint_t matrices, uint_t rowBytes, uint_t matrixBytes, uint_t dataType);
+void* Matrix(void* buf, size_t offset, void* desc);
+void* VectorDesc(uint_t length, uint_t dataType);
+void* Vector(void* buf, size_t offset, void* desc);
 void* MBuf2Buf(void* dst,  void* metalbuf, size_t len);
+void* MBufContents(void* mBuf);
 void* MakeComputeCommandEncoder(void* cmdbuf);
 void CmdBuf_Enqueue(void* cmdBuf);
-void RunBinFunc(void* commandbuffer, void* pipelineFunc, void* bufA, void* bufB, void* bufC, size_t arrlen);
+void CmdBuf_Commit(void* cmdBuf, uint64_t handle);
+void CmdBuf_WaitUntilCompleted(void* cmdBuf);
+void RunFunc(void* commandbuffer, void* pipelineFunc, void** bufs, const size_t* offs, int nbufs, const void* consts, const size_t* constsLens, int nconsts, size_t arrlen);
+void RunGroupFunc(void* commandbuffer, void* pipelineFunc, void* bufA, size_t offA, void* bufC, size_t offC, const void* consts, const size_t* constsLens, int nconsts, size_t groups, size_t threads);
 typedef struct Res {
 	void* Ptr; // the actual pointer to the object (library, function, computepipeline, etc)
 	const char* Err;
@@ -37,12 +44,15 @@ typedef struct Res {
 Res_t MakeLibrary(void* device, const char* src, size_t len);
 void* MakeFunction(void* lib, const char* name);
 Res_t MakeComputePipeline(void* device, void* function);
+size_t MaxThreadsPerThreadgroup(void* pipeline);
 
 /* Linalg */
-void* matmul(void* commandBuffer, void* matrixA, void* matrixB, void* matrixC, bool transA, bool transB);
+void* matmul(void* commandBuffer, void* matrixA, void* matrixB, void* matrixC, bool transA, bool transB, double alpha, double beta);
 void* matvecmul(void* commandBuffer, void* matrixA, void* vecB, void* vecC, bool transMat);
 /* NN */
 void* softmax(void* commandBuffer, void* mat, void* out);
 // TMP
 
 void printMatrix(void* in);
+
+#endif // MAGOL_H
@@ -5,6 +5,7 @@
 #import <Metal/Metal.h>
 #import <MetalPerformanceShaders/MetalPerformanceShaders.h>
 #include "magol.h"
+#include "_cgo_export.h"
 #include <stdio.h>
 #include <stdlib.h>
 
@@ -53,28 +54,32 @@ void* FreeMBuf(void* metalBuf){
 }
 
 // https://developer.apple.com/documentation/metalperformanceshaders/mpsmatrixdescriptor/2873331-matrixdescriptorwithrows?language=objc
-void* MatrixDesc(uint_t rows, uint_t cols, uint_t rowBytes){
+void* MatrixDesc(uint_t rows, uint_t cols, uint_t matrices, uint_t rowBytes, uint_t matrixBytes, uint_t dataType){
 	return [MPSMatrixDescriptor matrixDescriptorWithRows:(NSUInteger)rows
 						 columns:(NSUInteger)cols
+						matrices:(NSUInteger)matrices
 					        rowBytes:(NSUInteger)rowBytes
-						dataType:MPSDataTypeFloat32]; // TODO abstract data types
+					     matrixBytes:(NSUInteger)matrixBytes
+						dataType:(MPSDataType)dataType];
 }
 
 //https://developer.apple.com/documentation/metalperformanceshaders/mpsmatrix/2143201-initwithbuffer?language=objc
-void* Matrix(void* buf, void* desc){
+void* Matrix(void* buf, size_t offset, void* desc){
 	return [[MPSMatrix alloc] initWithBuffer:(id<MTLBuffer>)buf
+					  offset:(NSUInteger)offset
 				      descriptor:(MPSMatrixDescriptor*)desc];
 }
 
 // https://developer.apple.com/documentation/metalperformanceshaders/mpsvectordescriptor?language=objc
-void* VectorDesc(uint_t length){
+void* VectorDesc(uint_t length, uint_t dataType){
 	return [MPSVectorDescriptor vectorDescriptorWithLength:(NSUInteger)length
-						      dataType:MPSDataTypeFloat32];
+						      dataType:(MPSDataType)dataType];
 }
 
 // https://developer.apple.com/documentation/metalperformanceshaders/mpsvector/2873346-initwithbuffer?language=objc
-void* Vector(void* buf, void* desc) {
+void* Vector(void* buf, size_t offset, void* desc) {
 	return [[MPSVector alloc] initWithBuffer:(id<MTLBuffer>)buf
+					  offset:(NSUInteger)offset
 				      descriptor:(MPSVectorDescriptor*)desc];
 }
 
@@ -83,25 +88,47 @@ void* MBuf2Buf(void* dst,  void* metalbuf, size_t len) {
 	memcpy(dst, [mbuf contents], len);
 }
 
+void* MBufContents(void* metalbuf) {
+	return [(id<MTLBuffer>)metalbuf contents];
+}
 
 void CmdBuf_Enqueue(void* cmdBuf) {
 	[(id<MTLCommandBuffer>)cmdBuf enqueue];
 }
 
+// the handlers call back into Go with the handle of the Completion of the command buffer
+void CmdBuf_Commit(void* cmdBuf, uint64_t handle) {
+	id<MTLCommandBuffer> cb = (id<MTLCommandBuffer>)cmdBuf;
+	[cb addScheduledHandler:^(id<MTLCommandBuffer> b) {
+		magolCommandBufferScheduled(handle);
+	}];
+	[cb addCompletedHandler:^(id<MTLCommandBuffer> b) {
+		long code = 0;
+		char* msg = NULL;
+		if (b.error) {
+			code = b.error.code;
+			msg = (char*)b.error.localizedDescription.UTF8String;
+		}
+		magolCommandBufferCompleted(handle, (int)b.status, code, msg);
+	}];
+	[cb commit];
+}
+
+void CmdBuf_WaitUntilCompleted(void* cmdBuf) {
+	[(id<MTLCommandBuffer>)cmdBuf waitUntilCompleted];
+}
+
 void* MakeComputeCommandEncoder(void* cmdbuf) {
 	id<MTLComputeCommandEncoder> computeEncoder = [(id<MTLCommandBuffer>)cmdbuf computeCommandEncoder];
 	return computeEncoder;
 }
 
-void RunBinFunc(void* commandbuffer, void* pipelineFunc, void* bufA, void* bufB, void* bufC, size_t arrlen) {
-	id<MTLCommandBuffer> cmdbuf = (id<MTLCommandBuffer>)commandbuffer;
-	id<MTLComputePipelineState> pso = (id<MTLComputePipelineState>)pipelineFunc;
-	id<MTLComputeCommandEncoder> computeEncoder = [cmdbuf computeCommandEncoder];
-	[computeEncoder setComputePipelineState:pso];
-	[computeEncoder setBuffer:(id<MTLBuffer>)bufA offset:0 atIndex:0];
-	[computeEncoder setBuffer:(id<MTLBuffer>)bufB offset:0 atIndex:1];
-	[computeEncoder setBuffer:(id<MTLBuffer>)bufC offset:0 atIndex:2];
-
+// dispatch1D dispatches one thread per element, then ends the encoding.
+static void dispatch1D(id<MTLComputeCommandEncoder> computeEncoder, id<MTLComputePipelineState> pso, size_t arrlen) {
+	if (arrlen == 0) {
+		[computeEncoder endEncoding];
+		return;
+	}
 	NSUInteger len = (NSUInteger)arrlen;
 	MTLSize gridSize = MTLSizeMake(len, 1, 1);
 	NSUInteger threadGroupSize = pso.maxTotalThreadsPerThreadgroup;
@@ -116,10 +143,45 @@ void RunBinFunc(void* commandbuffer, void* pipelineFunc, void* bufA, void* bufB,
 
 	// end compute pass
 	[computeEncoder endEncoding];
+}
 
-	[cmdbuf commit];
+// setConsts binds each of the nconsts constants packed in consts to consecutive indices, starting at index.
+// The constants are copied into the command buffer, so they need not outlive the call.
+static void setConsts(id<MTLComputeCommandEncoder> computeEncoder, const void* consts, const size_t* constsLens, int nconsts, NSUInteger index) {
+	const char* p = (const char*)consts;
+	for (int i = 0; i < nconsts; i++) {
+		[computeEncoder setBytes:p length:constsLens[i] atIndex:index+i];
+		p += constsLens[i];
+	}
+}
+
+// RunFunc binds the nbufs buffers to the first indices, followed by the constants, and dispatches arrlen threads.
+void RunFunc(void* commandbuffer, void* pipelineFunc, void** bufs, const size_t* offs, int nbufs, const void* consts, const size_t* constsLens, int nconsts, size_t arrlen) {
+	id<MTLCommandBuffer> cmdbuf = (id<MTLCommandBuffer>)commandbuffer;
+	id<MTLComputePipelineState> pso = (id<MTLComputePipelineState>)pipelineFunc;
+	id<MTLComputeCommandEncoder> computeEncoder = [cmdbuf computeCommandEncoder];
+	[computeEncoder setComputePipelineState:pso];
+	for (int i = 0; i < nbufs; i++) {
+		[computeEncoder setBuffer:(id<MTLBuffer>)bufs[i] offset:offs[i] atIndex:i];
+	}
+	setConsts(computeEncoder, consts, constsLens, nconsts, nbufs);
+	dispatch1D(computeEncoder, pso, arrlen);
+}
 
-	[cmdbuf waitUntilCompleted];
+// RunGroupFunc dispatches groups threadgroups of threads threads each, for kernels that cooperate within a threadgroup.
+void RunGroupFunc(void* commandbuffer, void* pipelineFunc, void* bufA, size_t offA, void* bufC, size_t offC, const void* consts, const size_t* constsLens, int nconsts, size_t groups, size_t threads) {
+	id<MTLCommandBuffer> cmdbuf = (id<MTLCommandBuffer>)commandbuffer;
+	id<MTLComputePipelineState> pso = (id<MTLComputePipelineState>)pipelineFunc;
+	id<MTLComputeCommandEncoder> computeEncoder = [cmdbuf computeCommandEncoder];
+	[computeEncoder setComputePipelineState:pso];
+	[computeEncoder setBuffer:(id<MTLBuffer>)bufA offset:offA atIndex:0];
+	[computeEncoder setBuffer:(id<MTLBuffer>)bufC offset:offC atIndex:1];
+	setConsts(computeEncoder, consts, constsLens, nconsts, 2);
+	if (groups > 0) {
+		[computeEncoder dispatchThreadgroups:MTLSizeMake(groups, 1, 1)
+			       threadsPerThreadgroup:MTLSizeMake(threads, 1, 1)];
+	}
+	[computeEncoder endEncoding];
 }
 
 Res_t MakeLibrary(void* device, const char* src, size_t len) {
@@ -149,23 +211,28 @@ Res_t MakeComputePipeline(void* device, void* function) {
 	return retVal;
 }
 
+size_t MaxThreadsPerThreadgroup(void* pipeline) {
+	return ((id<MTLComputePipelineState>)pipeline).maxTotalThreadsPerThreadgroup;
+}
+
 
 /* LINALG */
 
-void* matmul(void* commandBuffer, void* matrixA, void* matrixB, void* matrixC, bool transA, bool transB) {
+void* matmul(void* commandBuffer, void* matrixA, void* matrixB, void* matrixC, bool transA, bool transB, double alpha, double beta) {
 	id<MTLCommandBuffer> cmdBuf = (id<MTLCommandBuffer>)commandBuffer;
 	MPSMatrix *A = (MPSMatrix*)matrixA;
 	MPSMatrix *B = (MPSMatrix*)matrixB;
 	MPSMatrix *C = (MPSMatrix*)matrixC;
     // Create a MPSMatrixMultiplication kernel
     MPSMatrixMultiplication* matrixMultiplication = [[MPSMatrixMultiplication alloc] initWithDevice:cmdBuf.device
-										      transposeLeft:transA // TODO abstract
-										     transposeRight:transB // TODO abstract
-											 resultRows:A.rows
-										      resultColumns:B.columns
-										    interiorColumns:A.columns
-											      alpha:1.0 // TODO abstract
-											       beta:0.0]; // TODO abstract
+										      transposeLeft:transA
+										     transposeRight:transB
+											 resultRows:C.rows
+										      resultColumns:C.columns
+										    interiorColumns:transA ? A.rows : A.columns
+											      alpha:alpha
+											       beta:beta];
+    matrixMultiplication.batchSize = C.matrices;
 
 
     // Perform matrix multiplication
@@ -173,9 +240,6 @@ void* matmul(void* commandBuffer, void* matrixA, void* matrixB, void* matrixC, b
 				     leftMatrix:A
 				    rightMatrix:B
 				   resultMatrix:C];
-
-    [cmdBuf commit]; // TODO THIS IS A BAD IDEA. MOVE CONTROL STRUCTURES OUT
-    [cmdBuf waitUntilCompleted];
 }
 
 void* matvecmul(void* commandBuffer, void* matrixA, void* vecB, void* vecC, bool transMat) {
@@ -184,20 +248,17 @@ void* matvecmul(void* commandBuffer, void* matrixA, void* vecB, void* vecC, bool
 	MPSVector *B = (MPSVector*)vecB;
 	MPSVector *C = (MPSVector*)vecC;
 
-	// create a MPSMatrixVectorMultiplication kernel
+	// create a MPSMatrixVectorMultiplication kernel. Its rows and columns are those of op(A)
 	MPSMatrixVectorMultiplication* matvecMul = [[MPSMatrixVectorMultiplication alloc] initWithDevice:cmdBuf.device
 											       transpose:transMat
-												    rows:A.rows
-												 columns:A.columns
+												    rows:transMat ? A.columns : A.rows
+												 columns:transMat ? A.rows : A.columns
 												   alpha:1.0
 												    beta:0.0];
 	[matvecMul encodeToCommandBuffer:cmdBuf
 			     inputMatrix:A
 			     inputVector:B
 			    resultVector:C];
-	[cmdBuf commit];
-	[cmdBuf waitUntilCompleted];
-
 }
 
 /* NN */
@@ -213,9 +274,6 @@ void* softmax(void* commandBuffer, void* mat, void* out) {
 	[softmax encodeToCommandBuffer:cmdBuf
 			   inputMatrix:A
 			  resultMatrix:B];
-
-	[cmdBuf commit]; // TODO THIS IS A BAD IDEA. MOVE CONTROL STRUCTURES OUT
-	[cmdBuf waitUntilCompleted];
 }
 
 /* TMP UTILITIES */
@@ -0,0 +1,172 @@
+package magol
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// GoSlice is a tensor.Memory over a plain Go slice.
+type GoSlice[T any] []T
+
+func (s GoSlice[T]) Uintptr() uintptr { return uintptr(unsafe.Pointer(&s[0])) }
+func (s GoSlice[T]) MemSize() uintptr {
+	var v T
+	return uintptr(len(s)) * unsafe.Sizeof(v)
+}
+
+// Memset sets every element of mem to val. The size of an element is the size of val's type.
+func (e *Engine) Memset(mem tensor.Memory, val interface{}) error {
+	pattern, err := bytesOfScalar(val)
+	if err != nil {
+		return errors.Wrap(err, "Memset()")
+	}
+	if dt, ok := mem.(dtyper); ok && dt.Dtype().Type != reflect.TypeOf(val) {
+		return errors.Errorf("Memset(): Cannot set a memory of %v with a value of %T", dt.Dtype(), val)
+	}
+	if mem.MemSize()%uintptr(len(pattern)) != 0 {
+		return errors.Errorf("Memset(): Memory of %d bytes is not a multiple of the size of %T", mem.MemSize(), val)
+	}
+	if buf, ok := e.deviceMem(mem); ok {
+		if err := e.Sync(); err != nil {
+			return errors.Wrap(err, "Memset()")
+		}
+		return e.b.Fill(buf, pattern)
+	}
+	fillBytes(hostMem(mem), pattern)
+	return nil
+}
+
+// Memclr sets all of mem to zero.
+func (e *Engine) Memclr(mem tensor.Memory) {
+	if buf, ok := e.deviceMem(mem); ok {
+		if err := e.Sync(); err != nil {
+			panic(err)
+		}
+		if err := e.b.Fill(buf, []byte{0}); err != nil {
+			panic(err)
+		}
+		return
+	}
+	fillBytes(hostMem(mem), []byte{0})
+}
+
+// Memcpy copies src into dst. Either may be device memory or host memory.
+func (e *Engine) Memcpy(dst, src tensor.Memory) error {
+	n := src.MemSize()
+	if dst.MemSize() < n {
+		n = dst.MemSize()
+	}
+	if n == 0 {
+		return nil
+	}
+	dbuf, dDev := e.deviceMem(dst)
+	sbuf, sDev := e.deviceMem(src)
+	dbuf, sbuf = dbuf.view(0, n), sbuf.view(0, n)
+	if dDev || sDev {
+		if err := e.Sync(); err != nil {
+			return errors.Wrap(err, "Memcpy()")
+		}
+	}
+	switch {
+	case dDev && sDev:
+		return e.b.Copy(dbuf, sbuf)
+	case dDev:
+		return e.b.CopyIn(dbuf, hostMem(src)[:n])
+	case sDev:
+		return e.b.CopyOut(hostMem(dst)[:n], sbuf)
+	default:
+		copy(hostMem(dst), hostMem(src)[:n])
+		return nil
+	}
+}
+
+// Accessible returns a Go accessible copy of mem if mem is device memory. Otherwise mem is returned as is.
+func (e *Engine) Accessible(mem tensor.Memory) (tensor.Memory, error) {
+	buf, ok := e.deviceMem(mem)
+	if !ok {
+		return mem, nil
+	}
+	if buf.sz == 0 {
+		return nil, errors.New("Accessible(): Cannot stage an empty memory")
+	}
+	if err := e.Sync(); err != nil {
+		return nil, errors.Wrap(err, "Accessible()")
+	}
+	staged := make(byteslice, buf.sz)
+	if err := e.b.CopyOut(staged, buf); err != nil {
+		return nil, errors.Wrap(err, "Accessible()")
+	}
+	return staged, nil
+}
+
+// deviceMem returns the Buffer backing mem, if mem is memory allocated by the Engine.
+func (e *Engine) deviceMem(mem tensor.Memory) (Buffer, bool) {
+	buf, err := e.bufferOf(mem)
+	return buf, err == nil
+}
+
+type dtyper interface {
+	Dtype() tensor.Dtype
+}
+
+// hostMem returns host memory as a []byte.
+func hostMem(mem tensor.Memory) []byte {
+	if mem.MemSize() == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(ptrOf(mem.Uintptr())), mem.MemSize())
+}
+
+func fillBytes(dst, pattern []byte) {
+	for i := 0; i < len(dst); i += len(pattern) {
+		copy(dst[i:], pattern)
+	}
+}
+
+func bytesOf[T any](v T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(&v)), unsafe.Sizeof(v))
+}
+
+// bytesOfScalar returns the in-memory representation of a scalar value.
+func bytesOfScalar(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case float32:
+		return bytesOf(v), nil
+	case float64:
+		return bytesOf(v), nil
+	case int:
+		return bytesOf(v), nil
+	case int8:
+		return bytesOf(v), nil
+	case int16:
+		return bytesOf(v), nil
+	case int32:
+		return bytesOf(v), nil
+	case int64:
+		return bytesOf(v), nil
+	case uint:
+		return bytesOf(v), nil
+	case uint8:
+		return bytesOf(v), nil
+	case uint16:
+		return bytesOf(v), nil
+	case uint32:
+		return bytesOf(v), nil
+	case uint64:
+		return bytesOf(v), nil
+	case bool:
+		return bytesOf(v), nil
+	case complex64:
+		return bytesOf(v), nil
+	case complex128:
+		return bytesOf(v), nil
+	case F16:
+		return bytesOf(v), nil
+	case BF16:
+		return bytesOf(v), nil
+	}
+	return nil, errors.Errorf("Unsupported scalar type %T", val)
+}
@@ -0,0 +1,136 @@
+package magol
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_Memset(t *testing.T) {
+	assert := assert.New(t)
+	e := NewEngineWithBackend(NewHostBackend())
+
+	cases := []struct {
+		val      interface{}
+		n        int
+		expected interface{}
+	}{
+		{float32(3.5), 4, []float32{3.5, 3.5, 3.5, 3.5}},
+		{float64(-1), 3, []float64{-1, -1, -1}},
+		{int(7), 2, []int{7, 7}},
+		{int32(-2), 5, []int32{-2, -2, -2, -2, -2}},
+		{uint8(255), 3, []uint8{255, 255, 255}},
+		{true, 3, []bool{true, true, true}},
+	}
+	for _, c := range cases {
+		dt := tensor.Dtype{Type: reflect.TypeOf(c.val)}
+		a := tensor.New(tensor.WithShape(c.n), tensor.Of(dt), tensor.WithEngine(e))
+		if err := e.Memset(a, c.val); err != nil {
+			t.Errorf("%T: %v", c.val, err)
+			continue
+		}
+		staged, err := e.Accessible(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tensor.New(tensor.WithShape(c.n), tensor.Of(dt), tensor.FromMemory(staged.Uintptr(), staged.MemSize()))
+		assert.Equal(c.expected, got.Data(), "%T", c.val)
+	}
+
+	// the value must match the dtype of the tensor
+	a := tensor.New(tensor.WithShape(2), tensor.Of(tensor.Float32), tensor.WithEngine(e))
+	assert.Error(e.Memset(a, float64(1)))
+
+	// the memory must be a multiple of the value's size
+	mem, err := e.Alloc(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(e.Memset(mem, float32(1)))
+}
+
+func TestEngine_Memclr(t *testing.T) {
+	e := NewEngineWithBackend(NewHostBackend())
+	a := engineTensor(t, e, []float32{1, 2, 3, 4}, 2, 2)
+	e.Memclr(a)
+	assert.Equal(t, []float32{0, 0, 0, 0}, readback(t, e, a).Data())
+
+	a = engineTensor(t, e, []float32{1, 2, 3, 4}, 2, 2)
+	a.Zero()
+	assert.Equal(t, []float32{0, 0, 0, 0}, readback(t, e, a).Data())
+}
+
+func TestEngine_Memcpy(t *testing.T) {
+	assert := assert.New(t)
+	e := NewEngineWithBackend(NewHostBackend())
+
+	// host → device
+	dev, err := e.Alloc(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Memcpy(dev, GoSlice[float32]{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	// device → device
+	dev2, err := e.Alloc(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Memcpy(dev2, dev); err != nil {
+		t.Fatal(err)
+	}
+
+	// device → host
+	host := make(GoSlice[float32], 4)
+	if err := e.Memcpy(host, dev2); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(GoSlice[float32]{1, 2, 3, 4}, host)
+
+	// device → byteslice
+	bs := make(byteslice, 8)
+	if err := e.Memcpy(bs, dev2); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(byteslice(bytesOf([2]float32{1, 2})), bs)
+
+	// host → host
+	host2 := make(GoSlice[float32], 4)
+	if err := e.Memcpy(host2, host); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(host, host2)
+
+	// tensors backed by the engine
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	b := a.Clone().(*tensor.Dense)
+	assert.Equal([]float32{1, 2, 3, 4, 5, 6}, readback(t, e, b).Data())
+
+	c := tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32))
+	tensor.Copy(c, a)
+	assert.Equal([]float32{1, 2, 3, 4, 5, 6}, c.Data())
+}
+
+func TestEngine_Accessible(t *testing.T) {
+	e := NewEngineWithBackend(NewHostBackend())
+
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	staged, err := e.Accessible(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tensor.New(tensor.WithShape(3), tensor.Of(tensor.Float32), tensor.FromMemory(staged.Uintptr(), staged.MemSize()))
+	assert.Equal(t, []float32{1, 2, 3}, got.Data())
+
+	// host memory is returned as is
+	host := GoSlice[float32]{1, 2, 3}
+	mem, err := e.Accessible(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, host, mem)
+}
@@ -0,0 +1,277 @@
+//go:build darwin
+// +build darwin
+
+package magol
+
+/*
+#cgo LDFLAGS: -framework Metal -framework CoreGraphics -framework Foundation -framework MetalPerformanceShaders
+#include <stdlib.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include "magol.h"
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var _ Backend = &MetalBackend{}
+
+// MetalBackend is a Backend that runs on a Metal device.
+//
+// All ops are encoded into a single pending command buffer, which is committed by Commit.
+type MetalBackend struct {
+	sync.Mutex
+	d *Device
+	q CommandQueue
+	l Library
+
+	psos    map[string]ComputePipeline
+	pending *CommandBuffer
+}
+
+// NewMetalBackend compiles the kernel library for the given device.
+func NewMetalBackend(d *Device) (*MetalBackend, error) {
+	src, names, err := genLibrary()
+	if err != nil {
+		return nil, err
+	}
+	l, err := d.MakeLibrary(src)
+	if err != nil {
+		return nil, err
+	}
+	psos := make(map[string]ComputePipeline)
+	for _, name := range names {
+		fn, err := l.MakeFunction(name)
+		if err != nil {
+			return nil, err
+		}
+		if psos[name], err = d.MakeComputePipeline(fn); err != nil {
+			return nil, errors.Wrapf(err, "Unable to make compute pipeline for %q", name)
+		}
+	}
+	return &MetalBackend{
+		d: d,
+		q: MakeCommandQueue(d),
+		l: l,
+
+		psos: psos,
+	}, nil
+}
+
+// NewEngine creates an Engine that runs on the given Metal device.
+func NewEngine(d *Device, opts ...EngineOpt) *Engine {
+	b, err := NewMetalBackend(d)
+	if err != nil {
+		panic(err)
+	}
+	return NewEngineWithBackend(b, opts...)
+}
+
+func (b *MetalBackend) Alloc(size int64) (Buffer, error) {
+	buf := AllocMBuf(b.d, size)
+	if buf.b == nil {
+		return Buffer{}, errors.Errorf("Unable to allocate %d bytes", size)
+	}
+	return buf, nil
+}
+
+func (b *MetalBackend) Free(buf Buffer) error { buf.Free(); return nil }
+
+// CopyIn, CopyOut, Copy and Fill work directly on the contents of the buffers, as all buffers are allocated in shared storage mode.
+
+func (b *MetalBackend) CopyIn(dst Buffer, src []byte) error {
+	if uintptr(len(src)) > dst.sz {
+		return errors.Errorf("Cannot copy %d bytes into a Buffer of %d bytes", len(src), dst.sz)
+	}
+	copy(hostBytes(dst), src)
+	return nil
+}
+
+func (b *MetalBackend) CopyOut(dst []byte, src Buffer) error {
+	if uintptr(len(dst)) > src.sz {
+		return errors.Errorf("Cannot copy %d bytes out of a Buffer of %d bytes", len(dst), src.sz)
+	}
+	copy(dst, hostBytes(src))
+	return nil
+}
+
+func (b *MetalBackend) Copy(dst, src Buffer) error {
+	if src.sz > dst.sz {
+		return errors.Errorf("Cannot copy %d bytes into a Buffer of %d bytes", src.sz, dst.sz)
+	}
+	copy(hostBytes(dst), hostBytes(src))
+	return nil
+}
+
+func (b *MetalBackend) Fill(dst Buffer, pattern []byte) error {
+	if len(pattern) == 0 {
+		return errors.New("Cannot fill a Buffer with an empty pattern")
+	}
+	fillBytes(hostBytes(dst), pattern)
+	return nil
+}
+
+func (b *MetalBackend) Dispatch(kernel string, n int, consts [][]byte, args ...Buffer) error {
+	pso, ok := b.psos[kernel]
+	if !ok {
+		return errors.Errorf("Kernel %q not found", kernel)
+	}
+	if len(args) == 0 {
+		return errors.Errorf("Expected buffers for %q", kernel)
+	}
+	cp, lens := packConsts(consts)
+	// the buffers are ObjC objects, not Go memory, so C may be handed a slice of them
+	bufs, offs := make([]unsafe.Pointer, len(args)), make([]C.size_t, len(args))
+	for i, arg := range args {
+		bufs[i], offs[i] = arg.b, C.size_t(arg.off)
+	}
+	b.Lock()
+	defer b.Unlock()
+	C.RunFunc(b.cmdBuf().b, pso.p,
+		&bufs[0], &offs[0], C.int(len(args)),
+		cp, &lens[0], C.int(len(consts)),
+		C.size_t(n))
+	return nil
+}
+
+func (b *MetalBackend) DispatchGroups(kernel string, groups int, consts [][]byte, args ...Buffer) error {
+	pso, ok := b.psos[kernel]
+	if !ok {
+		return errors.Errorf("Kernel %q not found", kernel)
+	}
+	if len(args) != 2 {
+		return errors.Errorf("Expected 2 buffers for %q. Got %d instead", kernel, len(args))
+	}
+	if max := int(C.MaxThreadsPerThreadgroup(pso.p)); max < reduceThreads {
+		return errors.Errorf("%q may only run %d threads per threadgroup. %d are required", kernel, max, reduceThreads)
+	}
+	cp, lens := packConsts(consts)
+	b.Lock()
+	defer b.Unlock()
+	C.RunGroupFunc(b.cmdBuf().b, pso.p,
+		args[0].b, C.size_t(args[0].off),
+		args[1].b, C.size_t(args[1].off),
+		cp, &lens[0], C.int(len(consts)),
+		C.size_t(groups), C.size_t(reduceThreads))
+	return nil
+}
+
+// packConsts packs the constants into one blob, along with their lengths, as C may not hold on to Go pointers.
+func packConsts(consts [][]byte) (unsafe.Pointer, []C.size_t) {
+	var blob []byte
+	lens := make([]C.size_t, len(consts)+1)
+	for i, c := range consts {
+		blob = append(blob, c...)
+		lens[i] = C.size_t(len(c))
+	}
+	if len(blob) == 0 {
+		return nil, lens
+	}
+	return unsafe.Pointer(&blob[0]), lens
+}
+
+func (b *MetalBackend) MatMul(a, bb, c Buffer, al, bl, cl MatrixLayout, transA, transB bool, alpha, beta float32) error {
+	A, err := b.matrix(a, al)
+	if err != nil {
+		return err
+	}
+	B, err := b.matrix(bb, bl)
+	if err != nil {
+		return err
+	}
+	CM, err := b.matrix(c, cl)
+	if err != nil {
+		return err
+	}
+	if transA {
+		A.ToggleTranspose()
+	}
+	if transB {
+		B.ToggleTranspose()
+	}
+	b.Lock()
+	defer b.Unlock()
+	return MPSMatMul(b.cmdBuf(), A, B, CM, alpha, beta)
+}
+
+func (b *MetalBackend) MatVecMul(a, x, y Buffer, al MatrixLayout, xl, yl VectorLayout, transA bool) error {
+	A, err := b.matrix(a, al)
+	if err != nil {
+		return err
+	}
+	v, err := b.vector(x, xl)
+	if err != nil {
+		return err
+	}
+	retVal, err := b.vector(y, yl)
+	if err != nil {
+		return err
+	}
+	A.trans = transA
+	b.Lock()
+	defer b.Unlock()
+	return MPSMatVecMul(b.cmdBuf(), A, v, retVal)
+}
+
+func (b *MetalBackend) SoftMax(x, out Buffer, xl, outl MatrixLayout) error {
+	A, err := b.matrix(x, xl)
+	if err != nil {
+		return err
+	}
+	Out, err := b.matrix(out, outl)
+	if err != nil {
+		return err
+	}
+	b.Lock()
+	defer b.Unlock()
+	return MPSSoftmax(b.cmdBuf(), A, Out)
+}
+
+// Commit commits the pending command buffer.
+func (b *MetalBackend) Commit() *Completion {
+	b.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.Unlock()
+
+	if pending == nil {
+		return completedCompletion()
+	}
+	return pending.Commit()
+}
+
+// Discard drops the pending command buffer without committing it.
+func (b *MetalBackend) Discard() {
+	b.Lock()
+	b.pending = nil
+	b.Unlock()
+}
+
+// cmdBuf returns the pending command buffer, creating one if there is none. The caller must hold the lock.
+func (b *MetalBackend) cmdBuf() CommandBuffer {
+	if b.pending == nil {
+		cb := b.q.CommandBuffer()
+		b.pending = &cb
+	}
+	return *b.pending
+}
+
+func (b *MetalBackend) matrix(buf Buffer, l MatrixLayout) (*Matrix, error) {
+	desc, err := layout2MDesc(l)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatrix(buf, desc), nil
+}
+
+func (b *MetalBackend) vector(buf Buffer, l VectorLayout) (*Vector, error) {
+	desc, err := layout2VDesc(l)
+	if err != nil {
+		return nil, err
+	}
+	return NewVector(buf, desc), nil
+}
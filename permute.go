@@ -0,0 +1,93 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Transpose moves the data of t, a transposed view, so that it is laid out with expStrides, as tensor.Dense's Transpose asks for.
+// Any permutation of the axes of t is supported. The data is permuted into a temporary on the device, then copied back into t.
+func (e *Engine) Transpose(t tensor.Tensor, expStrides []int) error {
+	if ok, err := e.onHost(func(std tensor.StdEng) error { return std.Transpose(t, expStrides) }, t); ok {
+		return errors.Wrap(err, "Transpose()")
+	}
+	if err := e.transpose(t, expStrides); err != nil {
+		return errors.Wrap(err, "Transpose()")
+	}
+	return nil
+}
+
+func (e *Engine) transpose(t tensor.Tensor, expStrides []int) error {
+	if err := e.checkValidDtype(t); err != nil {
+		return err
+	}
+	if len(expStrides) != t.Dims() {
+		return errors.Errorf("Expected %d strides. Got %v instead", t.Dims(), expStrides)
+	}
+	tmp, err := e.makeTensor(t.Shape(), t.Dtype())
+	if err != nil {
+		return err
+	}
+	defer e.release(tmp)
+	if err = e.copyStrided(tmp, expStrides, t); err != nil {
+		return err
+	}
+	kernel, err := stridedName("copy", false, t.Dtype())
+	if err != nil {
+		return err
+	}
+	return e.dispatch(kernel, t.Shape().TotalSize(), nil, tmp, t)
+}
+
+// copyStrided copies src into dst, laid out with dstStrides.
+// If src and dst are contiguous along different axes, the copy is made by permuteKernel, which reads and writes contiguously
+// a tile at a time. Otherwise it is made elementwise, by the strided copy kernel.
+func (e *Engine) copyStrided(dst tensor.Memory, dstStrides []int, src tensor.Tensor) error {
+	shape, srcStrides := src.Shape(), src.Strides()
+	r, c := contiguousAxis(shape, srcStrides), contiguousAxis(shape, dstStrides)
+	if r < 0 || c < 0 || r == c {
+		kernel, err := stridedName("copy", true, src.Dtype())
+		if err != nil {
+			return err
+		}
+		l, err := newStridedLayout(shape, dstStrides, srcStrides)
+		if err != nil {
+			return err
+		}
+		return e.dispatch(kernel, shape.TotalSize(), [][]byte{bytesOf(l)}, src, dst)
+	}
+
+	// the tiles span the axes r and c, and the threadgroups the others
+	kept := tensor.Shape{}
+	var keptSrc, keptDst []int
+	for d := range shape {
+		if d != r && d != c {
+			kept, keptSrc, keptDst = append(kept, shape[d]), append(keptSrc, srcStrides[d]), append(keptDst, dstStrides[d])
+		}
+	}
+	outer, err := newStridedLayout(kept, keptDst, keptSrc)
+	if err != nil {
+		return err
+	}
+	inner, err := newStridedLayout(tensor.Shape{shape[r], shape[c]}, []int{dstStrides[r], dstStrides[c]}, []int{srcStrides[r], srcStrides[c]})
+	if err != nil {
+		return err
+	}
+	kernel, err := permuteName(src.Dtype())
+	if err != nil {
+		return err
+	}
+	tiles := (shape[r] + permuteTile - 1) / permuteTile * ((shape[c] + permuteTile - 1) / permuteTile)
+	return e.dispatchGroups(kernel, kept.TotalSize()*tiles, [][]byte{bytesOf(outer), bytesOf(inner)}, src, dst)
+}
+
+// contiguousAxis returns the axis of shape along which the elements laid out with strides are contiguous, or -1 if there is none.
+// Axes of a single element are not considered.
+func contiguousAxis(shape tensor.Shape, strides []int) int {
+	for d := range shape {
+		if shape[d] > 1 && d < len(strides) && strides[d] == 1 {
+			return d
+		}
+	}
+	return -1
+}
@@ -0,0 +1,115 @@
+package magol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestContiguousAxis(t *testing.T) {
+	assert.Equal(t, 1, contiguousAxis(tensor.Shape{2, 3}, []int{3, 1}))
+	assert.Equal(t, 0, contiguousAxis(tensor.Shape{3, 2}, []int{1, 3}))
+	assert.Equal(t, 2, contiguousAxis(tensor.Shape{2, 1, 3}, []int{3, 1, 1}), "axes of a single element are skipped")
+	assert.Equal(t, -1, contiguousAxis(tensor.Shape{2, 3}, []int{6, 2}))
+}
+
+func TestEngine_Transpose(t *testing.T) {
+	cases := []struct {
+		shape tensor.Shape
+		axes  []int
+	}{
+		{tensor.Shape{3, 5}, []int{1, 0}},
+		{tensor.Shape{37, 21}, []int{1, 0}}, // partial tiles
+		{tensor.Shape{4, 18, 33}, []int{0, 2, 1}},
+		{tensor.Shape{4, 18, 33}, []int{2, 0, 1}},
+		{tensor.Shape{4, 18, 33}, []int{1, 0, 2}}, // the last axis stays put, and the copy is elementwise
+		{tensor.Shape{2, 3, 17, 5}, []int{3, 1, 0, 2}},
+		{tensor.Shape{2, 3, 17, 5}, []int{0, 2, 3, 1}},
+		{tensor.Shape{3, 1, 4, 2, 19}, []int{4, 2, 0, 1, 3}},
+		{tensor.Shape{2, 3, 2, 5, 20}, []int{1, 4, 3, 0, 2}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v%v", c.shape, c.axes), func(t *testing.T) {
+			e := newTestEngine(t)
+			backing := tensor.Range(tensor.Float32, 0, c.shape.TotalSize()).([]float32)
+			std := tensor.New(tensor.WithShape(c.shape...), tensor.WithBacking(append([]float32(nil), backing...)))
+			if err := std.T(c.axes...); err != nil {
+				t.Fatal(err)
+			}
+			if err := std.Transpose(); err != nil {
+				t.Fatal(err)
+			}
+
+			x := engineTensor(t, e, backing, c.shape...)
+			if err := x.T(c.axes...); err != nil {
+				t.Fatal(err)
+			}
+			if err := x.Transpose(); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, std.Shape().Eq(x.Shape()), "Expected %v. Got %v", std.Shape(), x.Shape())
+			assert.Equal(t, std.Strides(), x.Strides())
+			assert.Equal(t, std.Data(), readback(t, e, x).Data())
+
+			// Materialize permutes the same way, into a new tensor
+			y := engineTensor(t, e, backing, c.shape...)
+			if err := y.T(c.axes...); err != nil {
+				t.Fatal(err)
+			}
+			m, err := e.Materialize(y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, std.Data(), readback(t, e, m).Data(), "Materialize")
+		})
+	}
+}
+
+func TestEngine_TransposeDtypes(t *testing.T) {
+	e := newTestEngine(t)
+	for _, dt := range []tensor.Dtype{tensor.Int32, tensor.Int8, tensor.Uint8} {
+		std := tensor.New(tensor.WithShape(3, 20, 2), tensor.WithBacking(tensor.Range(dt, 0, 120)))
+		x := engineCopy(t, e, std)
+		for _, d := range []*tensor.Dense{std, x} {
+			if err := d.T(2, 0, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Transpose(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.Equal(t, std.Data(), readback(t, e, x).Data(), "%v", dt)
+	}
+
+	mask := []bool{true, false, false, true, true, false}
+	x := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(mask)))
+	if err := x.T(); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Transpose(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []bool{true, true, false, true, false, false}, readback(t, e, x).Data(), "Bool")
+
+	h := engineHalfTensor(t, e, Float16, []float32{1, 2, 3, 4, 5, 6}, 3, 2)
+	if err := h.T(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Transpose(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{1, 3, 5, 2, 4, 6}, readbackFloats(t, e, h), "Float16")
+
+	// Float64s are transposed by StdEng
+	e64 := newTestEngine(t, WithFloat64OnHost())
+	f := engineFloat64s(t, e64, []float64{1, 2, 3, 4, 5, 6}, 2, 3)
+	if err := f.T(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Transpose(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{1, 4, 2, 5, 3, 6}, readback(t, e64, f).Data(), "Float64")
+}
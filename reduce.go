@@ -0,0 +1,214 @@
+package magol
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Sum, Prod, Max and Min reduce a along the given axes, or along all of them if none are given.
+// Like tensor.StdEng, the reduced axes are removed from the shape of the result, so that reducing along all of them yields a scalar.
+// KeepDims reshapes the result so that it may be broadcast against a.
+
+func (e *Engine) Sum(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	return e.reduce("Sum()", "sum", a, along...)
+}
+
+func (e *Engine) Prod(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	return e.reduce("Prod()", "prod", a, along...)
+}
+
+func (e *Engine) Max(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	return e.reduce("Max()", "max", a, along...)
+}
+
+func (e *Engine) Min(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	return e.reduce("Min()", "min", a, along...)
+}
+
+// Mean computes the mean of a along the given axes, or of all of a if none are given.
+func (e *Engine) Mean(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	axes, err := reducedAxes(a.Shape(), along)
+	if err != nil {
+		return nil, errors.Wrap(err, "Mean()")
+	}
+	sum, err := e.reduce("Mean()", "sum", a, axes...)
+	if err != nil {
+		return nil, err
+	}
+	n := 1
+	for _, axis := range axes {
+		n *= a.Shape()[axis]
+	}
+	count, err := floatOf(float32(n), a.Dtype())
+	if err != nil {
+		e.release(sum)
+		return nil, errors.Wrap(err, "Mean()")
+	}
+	if _, err = e.DivScalar(sum, count, true, tensor.UseUnsafe()); err != nil {
+		e.release(sum)
+		return nil, errors.Wrap(err, "Mean()")
+	}
+	return sum, nil
+}
+
+// Argmax and Argmin find the indices of the largest and smallest elements of t along axis,
+// or the flat index of the element in all of t, as a scalar, if axis is tensor.AllAxes.
+// NaNs are larger and smaller than any other value, and ties go to the lowest index.
+// The indices are tensor.Ints unless the Engine was created WithIndexDtype.
+
+func (e *Engine) Argmax(t tensor.Tensor, axis int) (tensor.Tensor, error) {
+	return e.argOp("Argmax()", "argmax", t, axis)
+}
+
+func (e *Engine) Argmin(t tensor.Tensor, axis int) (tensor.Tensor, error) {
+	return e.argOp("Argmin()", "argmin", t, axis)
+}
+
+// KeepDims reshapes reduced, the result of reducing a tensor of the given shape along the given axes,
+// so that the reduced axes are kept with a size of 1, like NumPy's keepdims does.
+func KeepDims(reduced tensor.Tensor, shape tensor.Shape, along ...int) error {
+	axes, err := reducedAxes(shape, along)
+	if err != nil {
+		return err
+	}
+	kept := shape.Clone()
+	for _, axis := range axes {
+		kept[axis] = 1
+	}
+	if kept.TotalSize() != reduced.Shape().TotalSize() {
+		return errors.Errorf("A tensor of shape %v is not the result of reducing a tensor of shape %v along %v", reduced.Shape(), shape, along)
+	}
+	return reduced.Reshape(kept...)
+}
+
+// reducedAxes returns the sorted axes of shape to reduce along. No axes means all of them.
+func reducedAxes(shape tensor.Shape, along []int) ([]int, error) {
+	if len(along) == 0 {
+		axes := make([]int, len(shape))
+		for i := range axes {
+			axes[i] = i
+		}
+		return axes, nil
+	}
+	axes := append([]int(nil), along...)
+	sort.Ints(axes)
+	for i, axis := range axes {
+		if axis < 0 || axis >= len(shape) {
+			return nil, errors.Errorf("Cannot reduce along axis %d of a tensor of shape %v", axis, shape)
+		}
+		if i > 0 && axes[i-1] == axis {
+			return nil, errors.Errorf("Cannot reduce along axis %d more than once", axis)
+		}
+	}
+	return axes, nil
+}
+
+// reduce runs the reduction op along the given axes of a. fn is the name of the calling method, for errors.
+func (e *Engine) reduce(fn, op string, a tensor.Tensor, along ...int) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdReduceOps[op](std, a, along...)
+		return err
+	}, a); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if err = e.checkValidDtype(a); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	axes, err := reducedAxes(a.Shape(), along)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	kernel, err := reduceName(op, a.Dtype())
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.checkKernel(a.Dtype(), kernel); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	return e.reduceWith(fn, kernel, a, axes, a.Dtype())
+}
+
+// argOp runs the arg reduction op along axis of t. fn is the name of the calling method, for errors.
+func (e *Engine) argOp(fn, op string, t tensor.Tensor, axis int) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdArgOps[op](std, t, axis)
+		return err
+	}, t); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if err = e.checkValidDtype(t); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if e.indexDtype != tensor.Int && e.indexDtype != tensor.Int32 {
+		return nil, errors.Errorf("%v: Expected indices to be Ints or Int32s. Got %v instead", fn, e.indexDtype)
+	}
+	var along []int
+	if axis != tensor.AllAxes {
+		along = []int{axis}
+	}
+	axes, err := reducedAxes(t.Shape(), along)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	kernel, err := argName(op, e.indexDtype, t.Dtype())
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.checkKernel(t.Dtype(), kernel); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	return e.reduceWith(fn, kernel, t, axes, e.indexDtype)
+}
+
+// checkReduce checks that the library has the kernels of the reduction ops for the Dtype dt.
+func (e *Engine) checkReduce(dt tensor.Dtype, ops ...string) error {
+	for _, op := range ops {
+		kernel, err := reduceName(op, dt)
+		if err != nil {
+			return err
+		}
+		if err = e.checkKernel(dt, kernel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reduceWith runs the named reduction kernel along the sorted axes of a, into a new tensor of the given dtype.
+// fn is the name of the calling method, for errors.
+//
+// Each element of the result is reduced by its own threadgroup:
+// the outer layout maps the threadgroups to the kept axes of a and to the result, and the inner layout maps each thread to the reduced axes of a.
+func (e *Engine) reduceWith(fn, kernel string, a tensor.Tensor, axes []int, dt tensor.Dtype) (tensor.Tensor, error) {
+	shape, strides := a.Shape(), a.Strides()
+	kept, keptStrides := tensor.Shape{}, []int{}
+	var reduced tensor.Shape
+	var reducedStrides []int
+	for d := range shape {
+		if i := sort.SearchInts(axes, d); i < len(axes) && axes[i] == d {
+			reduced, reducedStrides = append(reduced, shape[d]), append(reducedStrides, strides[d])
+			continue
+		}
+		kept, keptStrides = append(kept, shape[d]), append(keptStrides, strides[d])
+	}
+
+	outer, err := newStridedLayout(kept, kept.CalcStrides(), keptStrides)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	inner, err := newStridedLayout(reduced, nil, reducedStrides)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	retVal, err := e.makeTensor(kept, dt)
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.dispatchGroups(kernel, kept.TotalSize(), [][]byte{bytesOf(outer), bytesOf(inner)}, a, retVal); err != nil {
+		e.release(retVal)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
@@ -0,0 +1,312 @@
+package magol
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_Reductions(t *testing.T) {
+	backing := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i*7)%24)/8 - 1.25
+	}
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a tensor.Tensor, along ...int) (tensor.Tensor, error)
+		std  func(a tensor.Tensor, along ...int) (tensor.Tensor, error)
+	}{
+		{"Sum", (*Engine).Sum, tensor.Sum},
+		{"Prod", (*Engine).Prod, stdProd},
+		{"Max", (*Engine).Max, tensor.StdEng{}.Max},
+		{"Min", (*Engine).Min, tensor.StdEng{}.Min},
+	}
+	alongs := [][]int{nil, {0}, {1}, {2}, {0, 2}, {2, 0}, {1, 2}, {0, 1, 2}}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			e := newTestEngine(t)
+			a := engineTensor(t, e, backing, 2, 3, 4)
+			for _, transposed := range []bool{false, true} {
+				std := stdTensor(backing, 2, 3, 4)
+				if transposed {
+					if err := a.T(2, 0, 1); err != nil {
+						t.Fatal(err)
+					}
+					if err := std.T(2, 0, 1); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for _, along := range alongs {
+					// StdEng sorts along in place
+					expected, err := op.std(std, append([]int(nil), along...)...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					c, err := op.eng(e, a, along...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.True(t, expected.Shape().Eq(c.Shape()), "%v along %v: expected shape %v. Got %v", op.name, along, expected.Shape(), c.Shape())
+					assert.True(t, allWithinRange(rowMajorData(t, expected), rowMajorData(t, readback(t, e, c)), closef32), "%v along %v, transposed: %t", op.name, along, transposed)
+				}
+			}
+		})
+	}
+}
+
+// stdProd is the product of a along the given axes, with the semantics of StdEng's other reductions.
+func stdProd(a tensor.Tensor, along ...int) (tensor.Tensor, error) {
+	if len(along) == 0 || len(along) == a.Dims() {
+		prod := float32(1)
+		for _, v := range a.Data().([]float32) {
+			prod *= v
+		}
+		return tensor.New(tensor.FromScalar(prod)), nil
+	}
+	retVal := a.(*tensor.Dense).Materialize()
+	sort.Sort(sort.Reverse(sort.IntSlice(along)))
+	for _, axis := range along {
+		var err error
+		if retVal, err = (tensor.StdEng{}).Reduce(func(a, b float32) float32 { return a * b }, retVal, axis, float32(1)); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
+
+func TestEngine_Mean(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+
+	c, err := e.Mean(a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{2, 5}, readback(t, e, c).Data())
+
+	if c, err = e.Mean(a); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, c.Shape().IsScalar())
+	assert.Equal(t, []float32{3.5}, rowMajorData(t, readback(t, e, c)))
+}
+
+func TestKeepDims(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+
+	mean, err := e.Mean(a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = KeepDims(mean, a.Shape(), 1); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{2, 1}, mean.Shape())
+	centered, err := e.Sub(a, mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{-1, 0, 1, -1, 0, 1}, readback(t, e, centered).Data())
+
+	sum, err := e.Sum(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = KeepDims(sum, a.Shape()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{1, 1}, sum.Shape())
+
+	assert.Error(t, KeepDims(sum, a.Shape(), 0), "a sum along all axes is not a sum along axis 0")
+}
+
+func TestEngine_ReduceErrors(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+
+	_, err := e.Sum(a, 2)
+	assert.Error(t, err, "axis out of range")
+	_, err = e.Sum(a, -1)
+	assert.Error(t, err, "negative axis")
+	_, err = e.Max(a, 1, 1)
+	assert.Error(t, err, "repeated axis")
+	_, err = e.Min(tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{1, 2})))
+	assert.Error(t, err, "Float64")
+
+	// the result is released when the reduction fails, here as the input is not in the memory of the Engine
+	live := liveAllocs(e)
+	_, err = e.Sum(tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)), 1)
+	if assert.Error(t, err, "Go memory") {
+		assert.Regexp(t, `^Sum\(\): Input 0: `, err.Error(), "the error should be wrapped once")
+	}
+	_, err = e.Mean(tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)))
+	assert.Error(t, err, "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the results should have been released")
+}
+
+// TestEngine_PairwiseSum checks that long sums are reduced pairwise rather than sequentially,
+// which keeps the rounding error of the sum of n elements in O(log n) rather than O(n).
+func TestEngine_PairwiseSum(t *testing.T) {
+	const n = 1 << 20
+	backing := make([]float32, n)
+	var exact float64
+	var sequential float32
+	for i := range backing {
+		backing[i] = 0.1
+		exact += float64(backing[i])
+		sequential += backing[i]
+	}
+	e := newTestEngine(t)
+	a := engineTensor(t, e, backing, n)
+	c, err := e.Sum(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := rowMajorData(t, readback(t, e, c))[0]
+	assert.Less(t, math.Abs(float64(sum)-exact), math.Abs(float64(sequential)-exact)/100)
+}
+
+func TestEngine_ArgOps(t *testing.T) {
+	// plenty of ties
+	backing := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i * 5) % 7)
+	}
+	ops := []struct {
+		name string
+		eng  func(e *Engine, a tensor.Tensor, axis int) (tensor.Tensor, error)
+		std  func(a tensor.Tensor, axis int) (tensor.Tensor, error)
+	}{
+		{"Argmax", (*Engine).Argmax, tensor.StdEng{}.Argmax},
+		{"Argmin", (*Engine).Argmin, tensor.StdEng{}.Argmin},
+	}
+	for _, op := range ops {
+		for _, index := range []tensor.Dtype{tensor.Int, tensor.Int32} {
+			t.Run(op.name+"/"+index.String(), func(t *testing.T) {
+				e := newTestEngine(t, WithIndexDtype(index))
+				a := engineTensor(t, e, backing, 2, 3, 4)
+				std := stdTensor(backing, 2, 3, 4)
+				for _, axis := range []int{0, 1, 2, tensor.AllAxes} {
+					expected, err := op.std(std, axis)
+					if err != nil {
+						t.Fatal(err)
+					}
+					c, err := op.eng(e, a, axis)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.Equal(t, index, c.Dtype())
+					assert.True(t, expected.Shape().Eq(c.Shape()), "along %d: expected shape %v. Got %v", axis, expected.Shape(), c.Shape())
+					assert.Equal(t, intData(t, expected), intData(t, readback(t, e, c)), "along %d", axis)
+				}
+
+				// transposed
+				if err := a.T(2, 0, 1); err != nil {
+					t.Fatal(err)
+				}
+				if err := std.T(2, 0, 1); err != nil {
+					t.Fatal(err)
+				}
+				expected, err := op.std(std.Materialize(), 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c, err := op.eng(e, a, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, intData(t, expected), intData(t, readback(t, e, c)), "transposed")
+			})
+		}
+	}
+}
+
+// intData returns the row major data of an Int or Int32 tensor as ints.
+func intData(t *testing.T, x tensor.Tensor) []int {
+	var retVal []int
+	switch data := x.Data().(type) {
+	case int:
+		retVal = []int{data}
+	case []int:
+		retVal = data
+	case int32:
+		retVal = []int{int(data)}
+	case []int32:
+		for _, v := range data {
+			retVal = append(retVal, int(v))
+		}
+	default:
+		t.Fatalf("Expected indices. Got %T", data)
+	}
+	return retVal
+}
+
+func TestEngine_ArgOpsLongAxis(t *testing.T) {
+	// longer than reduceThreads, with the extremes repeated across threads
+	const n = 3*reduceThreads + 5
+	backing := make([]float32, 2*n)
+	for i := range backing {
+		backing[i] = float32(i % 100)
+	}
+	e := newTestEngine(t)
+	a := engineTensor(t, e, backing, 2, n)
+	std := stdTensor(backing, 2, n)
+	for _, axis := range []int{1, tensor.AllAxes} {
+		c, err := e.Argmax(a, axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := tensor.StdEng{}.Argmax(std, axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, intData(t, expected), intData(t, readback(t, e, c)), "Argmax along %d", axis)
+		if c, err = e.Argmin(a, axis); err != nil {
+			t.Fatal(err)
+		}
+		if expected, err = (tensor.StdEng{}).Argmin(std, axis); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, intData(t, expected), intData(t, readback(t, e, c)), "Argmin along %d", axis)
+	}
+}
+
+func TestEngine_ArgOpsNaN(t *testing.T) {
+	nan := float32(math.NaN())
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, nan, 3, nan, 2, 3, 1, 2}, 2, 4)
+
+	c, err := e.Argmax(a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1, 1}, intData(t, readback(t, e, c)))
+	if c, err = e.Argmin(a, 1); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1, 2}, intData(t, readback(t, e, c)))
+}
+
+func TestEngine_ArgOpErrors(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	_, err := e.Argmax(a, 2)
+	assert.Error(t, err, "axis out of range")
+
+	e = newTestEngine(t, WithIndexDtype(tensor.Float32))
+	a = engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	_, err = e.Argmin(a, 0)
+	assert.Error(t, err, "Float32 indices")
+
+	// dtypes with no arg kernels fail before allocating the indices
+	e = newTestEngine(t)
+	i := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]int32{1, 2, 3, 4, 5, 6})))
+	live := liveAllocs(e)
+	_, err = e.Argmax(i, 1)
+	assert.EqualError(t, err, "Argmax(): Unsupported Dtype int32: there is no kernel argmax_i64_i32")
+	assert.Equal(t, live, liveAllocs(e), "nothing should have been allocated")
+}
@@ -0,0 +1,78 @@
+package magol
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// registry maps the contents addresses of live allocations back to the Buffers that own them.
+//
+// Tensors only carry the address of their data (see tensor.FromMemory), so this is how the Engine
+// finds the device buffer to bind when a tensor is passed into an op.
+type registry struct {
+	sync.RWMutex
+	bufs []Buffer // sorted by contents address
+}
+
+func (r *registry) insert(buf Buffer) error {
+	r.Lock()
+	defer r.Unlock()
+	i := r.search(buf.Uintptr())
+	if i < len(r.bufs) && r.bufs[i].Uintptr() == buf.Uintptr() {
+		return errors.Errorf("Memory at %#x is already registered", buf.Uintptr())
+	}
+	r.bufs = append(r.bufs, Buffer{})
+	copy(r.bufs[i+1:], r.bufs[i:])
+	r.bufs[i] = buf
+	return nil
+}
+
+// remove unregisters the allocation that starts exactly at ptr, and returns it.
+func (r *registry) remove(ptr uintptr) (Buffer, error) {
+	r.Lock()
+	defer r.Unlock()
+	i := r.search(ptr)
+	if i == len(r.bufs) || r.bufs[i].Uintptr() != ptr {
+		return Buffer{}, errors.Errorf("Memory at %#x is not the start of an allocation made by this Engine", ptr)
+	}
+	buf := r.bufs[i]
+	r.bufs = append(r.bufs[:i], r.bufs[i+1:]...)
+	return buf, nil
+}
+
+// lookup returns a view of the allocation containing [ptr, ptr+size).
+func (r *registry) lookup(ptr, size uintptr) (Buffer, error) {
+	r.RLock()
+	defer r.RUnlock()
+	// the allocation containing ptr is the last one starting at or before ptr
+	i := r.search(ptr + 1)
+	if i == 0 {
+		return Buffer{}, errors.Errorf("Memory at %#x was not allocated by this Engine", ptr)
+	}
+	owner := r.bufs[i-1]
+	start := owner.Uintptr()
+	end := start + owner.MemSize()
+	if ptr >= end && !(ptr == start && size == 0) {
+		return Buffer{}, errors.Errorf("Memory at %#x was not allocated by this Engine", ptr)
+	}
+	if ptr+size > end {
+		return Buffer{}, errors.Errorf("Memory at %#x of %d bytes overruns its allocation at %#x of %d bytes", ptr, size, start, owner.MemSize())
+	}
+	return owner.view(ptr-start, size), nil
+}
+
+// search returns the index of the first Buffer whose contents address is >= ptr.
+func (r *registry) search(ptr uintptr) int {
+	return sort.Search(len(r.bufs), func(i int) bool { return r.bufs[i].Uintptr() >= ptr })
+}
+
+// bufferOf returns the Buffer backing mem, which may be a Buffer, or a tensor (or view of a tensor) allocated by the Engine.
+func (e *Engine) bufferOf(mem tensor.Memory) (Buffer, error) {
+	if buf, ok := mem.(Buffer); ok {
+		return buf, nil
+	}
+	return e.reg.lookup(mem.Uintptr(), mem.MemSize())
+}
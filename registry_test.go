@@ -0,0 +1,71 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+	e := NewEngineWithBackend(NewHostBackend(), WithCacheLimit(0))
+
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	b := engineTensor(t, e, []float32{7, 8, 9}, 3)
+
+	// the contents address resolves to the owning Buffer
+	bufA, err := e.bufferOf(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(a.Uintptr(), bufA.Uintptr())
+	assert.Equal(uintptr(0), bufA.off)
+	bufB, err := e.bufferOf(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(bufA.b != bufB.b)
+
+	// interior pointers from views resolve to a view of the owning Buffer
+	row, err := a.Slice(tensor.S(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := e.bufferOf(row.(*tensor.Dense))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(view.b == bufA.b)
+	assert.Equal(uintptr(12), view.off)
+	assert.Equal(uintptr(12), view.MemSize())
+	assert.Equal([]float32{4, 5, 6}, f32s(view))
+
+	// unknown memory is an error
+	_, err = e.bufferOf(GoSlice[float32]{1, 2, 3})
+	assert.Error(err)
+
+	// memory overrunning its allocation is an error
+	_, err = e.reg.lookup(a.Uintptr()+12, 16)
+	assert.Error(err)
+
+	// only the start of an allocation may be freed
+	assert.Error(e.Free(row.(*tensor.Dense), 12))
+	assert.NoError(e.Free(a, 24))
+	_, err = e.bufferOf(a)
+	assert.Error(err)
+	assert.Error(e.Free(a, 24), "double free")
+
+	// b is unaffected
+	_, err = e.bufferOf(b)
+	assert.NoError(err)
+}
+
+func TestEngine_UnknownMemory(t *testing.T) {
+	e := NewEngineWithBackend(NewHostBackend())
+	a := engineTensor(t, e, []float32{1, 2, 3}, 3)
+	b := tensor.New(tensor.WithShape(3), tensor.WithEngine(e), tensor.Of(tensor.Float32), tensor.FromMemory(GoSlice[float32]{1, 2, 3}.Uintptr(), 12))
+
+	_, err := e.Add(a, b)
+	assert.Error(t, err)
+}
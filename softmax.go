@@ -0,0 +1,263 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// SoftMax computes the softmax of x along axis. A negative axis counts from the last one, like in tensor.StdEng.
+//
+// The softmax of rows of row major tensors is computed by the Backend's SoftMax, if it supports their Dtype.
+// Any other softmax is computed as exp(x - max(x)) / sum(exp(x - max(x))), with the max and the sum reduced along axis.
+func (e *Engine) SoftMax(x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.SoftMax(x, axis, opts...)
+		return err
+	}, x); ok {
+		return retVal, errors.Wrap(err, "SoftMax()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.SoftMax(x, axis) })
+	}
+	if err = e.checkValidDtype(x); err != nil {
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+	if err = e.checkReduce(x.Dtype(), "max", "sum"); err != nil {
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+	if axis, err = resolveAxis(axis, x.Dims()); err != nil {
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+	if retVal, err = e.prepResult(x, x.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+
+	if axis == x.Dims()-1 && retVal != x && isRowMajor(x) && isRowMajor(retVal) && isLinalgDtype(x.Dtype()) {
+		cols := x.Shape()[axis]
+		l := MatrixLayout{Rows: x.Shape().TotalSize() / cols, Cols: cols, RowBytes: cols * int(x.Dtype().Size()), Dtype: x.Dtype()}
+		bufs, err := e.buffersOf(x, retVal)
+		if err != nil {
+			e.releaseResult(retVal, x, opts)
+			return nil, errors.Wrap(err, "SoftMax()")
+		}
+		if err = e.encode(func() error { return e.b.SoftMax(bufs[0], bufs[1], l, l) }); err != nil {
+			e.releaseResult(retVal, x, opts)
+			return nil, errors.Wrap(err, "SoftMax()")
+		}
+		return retVal, nil
+	}
+
+	if err = e.shiftByMax("SoftMax()", x, retVal, axis); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, err
+	}
+	if _, err = e.Exp(retVal, tensor.UseUnsafe()); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+	sum, err := e.reduceKeepDims("SoftMax()", "sum", retVal, axis)
+	if err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, err
+	}
+	defer e.release(sum)
+	if _, err = e.Div(retVal, sum, tensor.UseUnsafe()); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, errors.Wrap(err, "SoftMax()")
+	}
+	return retVal, nil
+}
+
+// LogSoftMax computes the log of the softmax of x along axis, as (x - max(x)) - log(sum(exp(x - max(x)))).
+func (e *Engine) LogSoftMax(x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.LogSoftMax(x, axis, opts...)
+		return err
+	}, x); ok {
+		return retVal, errors.Wrap(err, "LogSoftMax()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.LogSoftMax(x, axis) })
+	}
+	if err = e.checkValidDtype(x); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	if err = e.checkReduce(x.Dtype(), "max", "sum"); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	if axis, err = resolveAxis(axis, x.Dims()); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	if retVal, err = e.prepResult(x, x.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+
+	if err = e.shiftByMax("LogSoftMax()", x, retVal, axis); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, err
+	}
+	exp, err := e.Exp(retVal)
+	if err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	sum, err := e.reduceKeepDims("LogSoftMax()", "sum", exp, axis)
+	e.release(exp)
+	if err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, err
+	}
+	defer e.release(sum)
+	if _, err = e.Log(sum, tensor.UseUnsafe()); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	if _, err = e.Sub(retVal, sum, tensor.UseUnsafe()); err != nil {
+		e.releaseResult(retVal, x, opts)
+		return nil, errors.Wrap(err, "LogSoftMax()")
+	}
+	return retVal, nil
+}
+
+// SoftMaxB computes the gradient of the input of SoftMax along axis, given its output and the gradient of its output,
+// as output * (grad - sum(output * grad)). If it is unsafe, the gradient is written into output.
+func (e *Engine) SoftMaxB(output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.SoftMaxB(output, grad, axis, opts...)
+		return err
+	}, output, grad); ok {
+		return retVal, errors.Wrap(err, "SoftMaxB()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.SoftMaxB(output, grad, axis) })
+	}
+	if axis, err = e.checkValidSoftMaxBInput(output, grad, axis); err != nil {
+		return nil, errors.Wrap(err, "SoftMaxB()")
+	}
+	if retVal, err = e.prepResult(output, output.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, "SoftMaxB()")
+	}
+
+	prod, err := e.Mul(output, grad)
+	if err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "SoftMaxB()")
+	}
+	sum, err := e.reduceKeepDims("SoftMaxB()", "sum", prod, axis)
+	e.release(prod)
+	if err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, err
+	}
+	diff, err := e.Sub(grad, sum)
+	e.release(sum)
+	if err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "SoftMaxB()")
+	}
+	defer e.release(diff)
+	if _, err = e.Mul(output, diff, tensor.WithReuse(retVal)); err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "SoftMaxB()")
+	}
+	return retVal, nil
+}
+
+// LogSoftMaxB computes the gradient of the input of LogSoftMax along axis, given its output and the gradient of its output,
+// as grad - exp(output) * sum(grad). If it is unsafe, the gradient is written into output.
+func (e *Engine) LogSoftMaxB(output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.LogSoftMaxB(output, grad, axis, opts...)
+		return err
+	}, output, grad); ok {
+		return retVal, errors.Wrap(err, "LogSoftMaxB()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.LogSoftMaxB(output, grad, axis) })
+	}
+	if axis, err = e.checkValidSoftMaxBInput(output, grad, axis); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMaxB()")
+	}
+	if retVal, err = e.prepResult(output, output.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, "LogSoftMaxB()")
+	}
+
+	sum, err := e.reduceKeepDims("LogSoftMaxB()", "sum", grad, axis)
+	if err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, err
+	}
+	defer e.release(sum)
+	exp, err := e.Exp(output)
+	if err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "LogSoftMaxB()")
+	}
+	defer e.release(exp)
+	if _, err = e.Mul(exp, sum, tensor.UseUnsafe()); err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "LogSoftMaxB()")
+	}
+	if _, err = e.Sub(grad, exp, tensor.WithReuse(retVal)); err != nil {
+		e.releaseResult(retVal, output, opts)
+		return nil, errors.Wrap(err, "LogSoftMaxB()")
+	}
+	return retVal, nil
+}
+
+// checkValidSoftMaxBInput checks the inputs of the backward passes, and resolves their axis.
+func (e *Engine) checkValidSoftMaxBInput(output, grad tensor.Tensor, axis int) (int, error) {
+	if err := e.checkValidDtype(output, grad); err != nil {
+		return 0, err
+	}
+	if err := e.checkReduce(output.Dtype(), "sum"); err != nil {
+		return 0, err
+	}
+	if !output.Shape().Eq(grad.Shape()) {
+		return 0, errors.Errorf("Expected output and grad to have the same shape. Got %v and %v instead", output.Shape(), grad.Shape())
+	}
+	return resolveAxis(axis, output.Dims())
+}
+
+// shiftByMax computes x - max(x) along axis into retVal, which may be x. fn is the name of the calling method, for errors.
+func (e *Engine) shiftByMax(fn string, x, retVal tensor.Tensor, axis int) error {
+	max, err := e.reduceKeepDims(fn, "max", x, axis)
+	if err != nil {
+		return err
+	}
+	defer e.release(max)
+	if _, err = e.Sub(x, max, tensor.WithReuse(retVal)); err != nil {
+		return errors.Wrap(err, fn)
+	}
+	return nil
+}
+
+// reduceKeepDims runs the reduction op along axis of a, keeping the axis so that the result may be broadcast against a.
+// fn is the name of the calling method, for errors.
+func (e *Engine) reduceKeepDims(fn, op string, a tensor.Tensor, axis int) (tensor.Tensor, error) {
+	kernel, err := reduceName(op, a.Dtype())
+	if err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	retVal, err := e.reduceWith(fn, kernel, a, []int{axis}, a.Dtype())
+	if err != nil {
+		return nil, err
+	}
+	if err = KeepDims(retVal, a.Shape(), axis); err != nil {
+		e.release(retVal)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
+
+// resolveAxis returns the axis of a tensor with the given number of dimensions. Negative axes count from the last one.
+func resolveAxis(axis, dims int) (int, error) {
+	resolved := axis
+	if resolved < 0 {
+		resolved += dims
+	}
+	if resolved < 0 || resolved >= dims {
+		return 0, errors.Errorf("Axis %d is out of range for a tensor of %d dimensions", axis, dims)
+	}
+	return resolved, nil
+}
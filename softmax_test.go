@@ -0,0 +1,287 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_SoftMaxAxes(t *testing.T) {
+	backing := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i*7)%24)/4 - 3
+	}
+	shapes := []tensor.Shape{{24}, {4, 6}, {2, 3, 4}, {2, 1, 3, 4}}
+	for _, shape := range shapes {
+		for axis := -1; axis < shape.Dims(); axis++ {
+			std := stdTensor(backing, shape...)
+			expected, err := tensor.StdEng{}.SoftMax(std, axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expected.Data().([]float32)
+
+			e := newTestEngine(t)
+			x := engineTensor(t, e, backing, shape...)
+			out, err := e.SoftMax(x, axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, allWithinRange(want, readback(t, e, out).Data().([]float32), closef32), "%v along %d", shape, axis)
+			assert.Equal(t, backing, readback(t, e, x).Data(), "%v along %d: x should be untouched", shape, axis)
+
+			reuse := engineTensor(t, e, make([]float32, len(backing)), shape...)
+			if out, err = e.SoftMax(x, axis, tensor.WithReuse(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, out == reuse)
+			assert.True(t, allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "%v along %d, reuse", shape, axis)
+
+			if out, err = e.SoftMax(x, axis, tensor.UseUnsafe()); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, out == x)
+			assert.True(t, allWithinRange(want, readback(t, e, x).Data().([]float32), closef32), "%v along %d, unsafe", shape, axis)
+		}
+	}
+}
+
+func TestEngine_SoftMaxTransposed(t *testing.T) {
+	backing := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i*5)%24)/4 - 3
+	}
+	for axis := 0; axis < 3; axis++ {
+		e := newTestEngine(t)
+		x := engineTensor(t, e, backing, 2, 3, 4)
+		if err := x.T(2, 0, 1); err != nil {
+			t.Fatal(err)
+		}
+		std := stdTensor(backing, 2, 3, 4)
+		if err := std.T(2, 0, 1); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := tensor.StdEng{}.SoftMax(std.Materialize(), axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := e.SoftMax(x, axis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, allWithinRange(expected.Data().([]float32), readback(t, e, out).Data().([]float32), closef32), "along %d", axis)
+
+		// in place, into the view
+		if _, err = e.SoftMax(x, axis, tensor.UseUnsafe()); err != nil {
+			t.Fatal(err)
+		}
+		m, err := e.Materialize(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, allWithinRange(expected.Data().([]float32), readback(t, e, m).Data().([]float32), closef32), "along %d, unsafe", axis)
+	}
+}
+
+func TestEngine_SoftMaxErrors(t *testing.T) {
+	e := newTestEngine(t)
+	x := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	_, err := e.SoftMax(x, 2)
+	assert.Error(t, err)
+	_, err = e.SoftMax(x, -3)
+	assert.Error(t, err)
+	_, err = e.SoftMax(x, 1, tensor.WithReuse(engineTensor(t, e, []float32{1, 2, 3, 4}, 2, 2)))
+	assert.Error(t, err)
+
+	// the result is released when the op fails, here as x is not in the memory of the Engine
+	live := liveAllocs(e)
+	for _, axis := range []int{0, 1} {
+		_, err = e.SoftMax(tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32)), axis)
+		assert.Error(t, err, "Go memory, along axis %d", axis)
+	}
+	assert.Equal(t, live, liveAllocs(e), "the results should have been released")
+}
+
+func TestEngine_LogSoftMaxAxes(t *testing.T) {
+	backing := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i*7)%24)/4 - 3
+	}
+	for _, shape := range []tensor.Shape{{24}, {4, 6}, {2, 3, 4}} {
+		for axis := -1; axis < shape.Dims(); axis++ {
+			expected, err := tensor.StdEng{}.LogSoftMax(stdTensor(backing, shape...), axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expected.Data().([]float32)
+
+			e := newTestEngine(t)
+			x := engineTensor(t, e, backing, shape...)
+			out, err := e.LogSoftMax(x, axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, allWithinRange(want, readback(t, e, out).Data().([]float32), closef32), "%v along %d", shape, axis)
+
+			reuse := engineTensor(t, e, make([]float32, len(backing)), shape...)
+			if out, err = e.LogSoftMax(x, axis, tensor.WithReuse(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, out == reuse)
+			assert.True(t, allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "%v along %d, reuse", shape, axis)
+
+			if out, err = e.LogSoftMax(x, axis, tensor.UseUnsafe()); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, out == x)
+			assert.True(t, allWithinRange(want, readback(t, e, x).Data().([]float32), closef32), "%v along %d, unsafe", shape, axis)
+		}
+	}
+}
+
+func TestEngine_LogSoftMaxStable(t *testing.T) {
+	e := newTestEngine(t)
+	x := engineTensor(t, e, []float32{1000, 1001, 1002, -1000, -1001, -1002}, 2, 3)
+	out, err := e.LogSoftMax(x, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.InDeltaSlice(t, []float32{-2.407606, -1.407606, -0.407606, -0.407606, -1.407606, -2.407606}, readback(t, e, out).Data(), 1e-5)
+}
+
+func TestEngine_SoftMaxBAxes(t *testing.T) {
+	backing := make([]float32, 24)
+	gradBacking := make([]float32, 24)
+	for i := range backing {
+		backing[i] = float32((i*7)%24)/4 - 3
+		gradBacking[i] = float32((i*5)%24)/8 - 1
+	}
+	ops := []struct {
+		name    string
+		forward func(x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		eng     func(e *Engine, output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std     func(output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"SoftMaxB", tensor.StdEng{}.SoftMax, (*Engine).SoftMaxB, tensor.StdEng{}.SoftMaxB},
+		{"LogSoftMaxB", tensor.StdEng{}.LogSoftMax, (*Engine).LogSoftMaxB, tensor.StdEng{}.LogSoftMaxB},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for _, shape := range []tensor.Shape{{24}, {4, 6}, {2, 3, 4}} {
+				for axis := -1; axis < shape.Dims(); axis++ {
+					output, err := op.forward(stdTensor(backing, shape...), axis)
+					if err != nil {
+						t.Fatal(err)
+					}
+					outBacking := output.Data().([]float32)
+					expected, err := op.std(output, stdTensor(gradBacking, shape...), axis)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want := expected.Data().([]float32)
+
+					e := newTestEngine(t)
+					out := engineTensor(t, e, outBacking, shape...)
+					grad := engineTensor(t, e, gradBacking, shape...)
+					dx, err := op.eng(e, out, grad, axis)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.True(t, allWithinRange(want, readback(t, e, dx).Data().([]float32), closef32), "%v along %d", shape, axis)
+
+					reuse := engineTensor(t, e, make([]float32, len(backing)), shape...)
+					if dx, err = op.eng(e, out, grad, axis, tensor.WithReuse(reuse)); err != nil {
+						t.Fatal(err)
+					}
+					assert.True(t, dx == reuse)
+					assert.True(t, allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "%v along %d, reuse", shape, axis)
+
+					if dx, err = op.eng(e, out, grad, axis, tensor.UseUnsafe()); err != nil {
+						t.Fatal(err)
+					}
+					assert.True(t, dx == out)
+					assert.True(t, allWithinRange(want, readback(t, e, out).Data().([]float32), closef32), "%v along %d, unsafe", shape, axis)
+				}
+			}
+		})
+	}
+}
+
+// TestEngine_SoftMaxGradCheck compares the backward passes with finite differences of the forward passes.
+// The loss is the sum of the outputs weighted by w, so that the gradient of the outputs is w.
+func TestEngine_SoftMaxGradCheck(t *testing.T) {
+	const h = 1e-2
+	shape := tensor.Shape{2, 3, 4}
+	backing := make([]float32, shape.TotalSize())
+	w := make([]float32, shape.TotalSize())
+	for i := range backing {
+		backing[i] = float32((i*7)%24)/8 - 1.5
+		w[i] = float32((i*5)%24)/12 - 1
+	}
+	ops := []struct {
+		name     string
+		forward  func(e *Engine, x tensor.Tensor, axis int, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		backward func(e *Engine, output, grad tensor.Tensor, axis int, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"SoftMax", (*Engine).SoftMax, (*Engine).SoftMaxB},
+		{"LogSoftMax", (*Engine).LogSoftMax, (*Engine).LogSoftMaxB},
+	}
+	for _, op := range ops {
+		for axis := 0; axis < shape.Dims(); axis++ {
+			e := newTestEngine(t)
+			loss := func(x []float32) float64 {
+				out, err := op.forward(e, engineTensor(t, e, x, shape...), axis)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var l float64
+				for i, v := range readback(t, e, out).Data().([]float32) {
+					l += float64(w[i]) * float64(v)
+				}
+				return l
+			}
+
+			out, err := op.forward(e, engineTensor(t, e, backing, shape...), axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dx, err := op.backward(e, out, engineTensor(t, e, w, shape...), axis)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			numeric := make([]float32, len(backing))
+			x := append([]float32(nil), backing...)
+			for i := range x {
+				x[i] = backing[i] + h
+				plus := loss(x)
+				x[i] = backing[i] - h
+				minus := loss(x)
+				x[i] = backing[i]
+				numeric[i] = float32((plus - minus) / (2 * h))
+			}
+			assert.InDeltaSlice(t, numeric, readback(t, e, dx).Data(), 1e-3, "%v along %d", op.name, axis)
+		}
+	}
+}
+
+func TestEngine_SoftMaxBErrors(t *testing.T) {
+	e := newTestEngine(t)
+	out := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	_, err := e.SoftMaxB(out, engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 3, 2), 1)
+	assert.Error(t, err, "shape mismatch")
+	_, err = e.LogSoftMaxB(out, engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3), 2)
+	assert.Error(t, err, "axis out of range")
+
+	// the results are released when the ops fail, here as the inputs are not in the memory of the Engine
+	live := liveAllocs(e)
+	goMem := tensor.New(tensor.WithShape(2, 3), tensor.Of(tensor.Float32))
+	_, err = e.LogSoftMax(goMem, 1)
+	assert.Error(t, err, "LogSoftMax")
+	_, err = e.SoftMaxB(goMem, goMem, 1)
+	assert.Error(t, err, "SoftMaxB")
+	_, err = e.LogSoftMaxB(goMem, goMem, 1)
+	assert.Error(t, err, "LogSoftMaxB")
+	assert.Equal(t, live, liveAllocs(e), "the results should have been released")
+}
@@ -0,0 +1,159 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// isRowMajor reports whether t is laid out contiguously in row major order, so that kernels can index it flatly.
+func isRowMajor(t tensor.Tensor) bool {
+	shape, strides := t.Shape(), t.Strides()
+	if len(strides) < len(shape) {
+		return false
+	}
+	expected := 1
+	for d := len(shape) - 1; d >= 0; d-- {
+		if shape[d] != 1 && strides[d] != expected {
+			return false
+		}
+		expected *= shape[d]
+	}
+	return true
+}
+
+// Materialize returns a row major, contiguous copy of t, made on the device.
+// t is returned as is if it already is row major and contiguous.
+//
+// The Engine's ops work with transposed views, slices and column major tensors directly. Materialize is for when
+// a contiguous layout is required elsewhere, and is what the Engine falls back to when an op can't be expressed
+// with the strides of its operands (e.g. a matrix multiplication of a matrix strided along both axes).
+func (e *Engine) Materialize(t tensor.Tensor) (tensor.Tensor, error) {
+	if isRowMajor(t) {
+		return t, nil
+	}
+	retVal, err := e.materialize(t)
+	if err != nil {
+		return nil, errors.Wrap(err, "Materialize()")
+	}
+	return retVal, nil
+}
+
+func (e *Engine) materialize(t tensor.Tensor) (tensor.DenseTensor, error) {
+	retVal, err := e.makeTensor(t.Shape(), t.Dtype())
+	if err != nil {
+		return nil, err
+	}
+	if err = e.copyInto(retVal, t); err != nil {
+		e.release(retVal)
+		return nil, err
+	}
+	return retVal, nil
+}
+
+// copyInto copies src into dst, which has the same shape, following the strides of both.
+func (e *Engine) copyInto(dst, src tensor.Tensor) error {
+	if !dst.Shape().Eq(src.Shape()) {
+		return errors.Errorf("Cannot copy a tensor of shape %v into a tensor of shape %v", src.Shape(), dst.Shape())
+	}
+	return e.copyStrided(dst, dst.Strides(), src)
+}
+
+// release frees a temporary tensor made by the Engine.
+//
+// Temporaries can be released as soon as the ops using them are encoded. Their memory is only freed once
+// those ops are done, as the memory may otherwise be handed out, and written to by the host, while they still use it.
+func (e *Engine) release(t tensor.Tensor) {
+	e.q.Lock()
+	e.q.released = append(e.q.released, t)
+	e.q.Unlock()
+}
+
+// matrixOperand returns t, or a row major copy of it if its layout can't be described by a MatrixLayout,
+// along with its layout. If allowTrans is false, transposed matrices are materialized too.
+// The returned tensor must be released if it isn't t.
+func (e *Engine) matrixOperand(t tensor.DenseTensor, allowTrans bool) (m tensor.DenseTensor, l MatrixLayout, trans bool, err error) {
+	return e.layoutOperand(t, allowTrans, matrixLayout)
+}
+
+// batchOperand is matrixOperand for 3D tensors holding batches of matrices.
+func (e *Engine) batchOperand(t tensor.DenseTensor, allowTrans bool) (m tensor.DenseTensor, l MatrixLayout, trans bool, err error) {
+	return e.layoutOperand(t, allowTrans, batchLayout)
+}
+
+func (e *Engine) layoutOperand(t tensor.DenseTensor, allowTrans bool, layout func(tensor.DenseTensor) (MatrixLayout, bool, error)) (m tensor.DenseTensor, l MatrixLayout, trans bool, err error) {
+	l, trans, err = layout(t)
+	if err == nil && (allowTrans || !trans) {
+		return t, l, trans, nil
+	}
+	if m, err = e.materialize(t); err != nil {
+		return nil, l, false, err
+	}
+	l, _, err = layout(m)
+	return m, l, false, err
+}
+
+// matrixResult returns the tensor the result of a matrix op is written into, along with its layout:
+// t itself, or a row major temporary if t's layout can't be described by a MatrixLayout
+// (or is transposed, if allowTrans is false). The temporary is copied into t and released by finishResult.
+func (e *Engine) matrixResult(t tensor.DenseTensor, allowTrans bool) (tensor.DenseTensor, MatrixLayout, bool, error) {
+	return e.layoutResult(t, allowTrans, matrixLayout)
+}
+
+// batchResult is matrixResult for 3D tensors holding batches of matrices.
+func (e *Engine) batchResult(t tensor.DenseTensor, allowTrans bool) (tensor.DenseTensor, MatrixLayout, bool, error) {
+	return e.layoutResult(t, allowTrans, batchLayout)
+}
+
+func (e *Engine) layoutResult(t tensor.DenseTensor, allowTrans bool, layout func(tensor.DenseTensor) (MatrixLayout, bool, error)) (tensor.DenseTensor, MatrixLayout, bool, error) {
+	l, trans, err := layout(t)
+	if err == nil && (allowTrans || !trans) {
+		return t, l, trans, nil
+	}
+	tmp, err := e.makeTensor(t.Shape(), t.Dtype())
+	if err != nil {
+		return nil, l, false, err
+	}
+	l, _, err = layout(tmp)
+	return tmp, l, false, err
+}
+
+// vectorOperand returns t, or a contiguous copy of it if it is strided. The returned tensor must be released if it isn't t.
+func (e *Engine) vectorOperand(t tensor.DenseTensor) (tensor.DenseTensor, VectorLayout, error) {
+	if !isRowMajor(t) {
+		var err error
+		if t, err = e.materialize(t); err != nil {
+			return nil, VectorLayout{}, err
+		}
+	}
+	l, err := vectorLayout(t)
+	return t, l, err
+}
+
+// vectorResult returns the tensor the result of a vector op is written into: t itself,
+// or a contiguous temporary if t is strided. The temporary is copied into t and released by finishResult.
+func (e *Engine) vectorResult(t tensor.DenseTensor) (tensor.DenseTensor, VectorLayout, error) {
+	if !isRowMajor(t) {
+		var err error
+		if t, err = e.makeTensor(t.Shape(), t.Dtype()); err != nil {
+			return nil, VectorLayout{}, err
+		}
+	}
+	l, err := vectorLayout(t)
+	return t, l, err
+}
+
+// discardResult releases result, the temporary made for t by matrixResult, batchResult or vectorResult, if the op failed before finishResult.
+func (e *Engine) discardResult(t, result tensor.DenseTensor) {
+	if result != t {
+		e.release(result)
+	}
+}
+
+// finishResult copies a temporary result into t and releases it. It does nothing if the result was written into t directly.
+func (e *Engine) finishResult(t, result tensor.DenseTensor) error {
+	if result == t {
+		return nil
+	}
+	defer e.release(result)
+	return e.copyInto(t, result)
+}
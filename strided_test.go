@@ -0,0 +1,331 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+// engineColMajor creates a column major tensor backed by engine memory, filled with the given raw backing.
+func engineColMajor(t *testing.T, e *Engine, raw []float32, shape ...int) *tensor.Dense {
+	rm := engineTensor(t, e, raw, shape...)
+	return tensor.New(tensor.WithShape(shape...), tensor.Of(tensor.Float32), tensor.WithEngine(e), tensor.FromMemory(rm.Uintptr(), rm.MemSize()), tensor.AsFortran(nil))
+}
+
+// stdColMajor is the tensor.StdEng equivalent of engineColMajor.
+func stdColMajor(raw []float32, shape ...int) *tensor.Dense {
+	return tensor.New(tensor.WithShape(shape...), tensor.WithBacking(append([]float32(nil), raw...)), tensor.AsFortran(nil))
+}
+
+func stdTensor(backing []float32, shape ...int) *tensor.Dense {
+	return tensor.New(tensor.WithShape(shape...), tensor.WithBacking(append([]float32(nil), backing...)))
+}
+
+// rowMajorData returns the elements of x in row major order, whatever its layout.
+func rowMajorData(t *testing.T, x tensor.Tensor) []float32 {
+	shape := x.Shape()
+	retVal := make([]float32, 0, shape.TotalSize())
+	coords := make([]int, len(shape))
+	for i := 0; i < shape.TotalSize(); i++ {
+		v, err := x.At(coords...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retVal = append(retVal, v.(float32))
+		for d := len(coords) - 1; d >= 0; d-- {
+			if coords[d]++; coords[d] < shape[d] {
+				break
+			}
+			coords[d] = 0
+		}
+	}
+	return retVal
+}
+
+func TestIsRowMajor(t *testing.T) {
+	a := stdTensor(make([]float32, 12), 3, 4)
+	assert.True(t, isRowMajor(a))
+	rows, err := a.Slice(tensor.S(1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, isRowMajor(rows))
+	col, err := a.Slice(nil, tensor.S(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, isRowMajor(col))
+	if err = a.T(); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, isRowMajor(a))
+	assert.False(t, isRowMajor(stdColMajor(make([]float32, 6), 2, 3)))
+}
+
+func TestEngine_StridedBinOps(t *testing.T) {
+	backingA := tensor.Range(tensor.Float32, 1, 13).([]float32)
+	backingB := tensor.Range(tensor.Float32, 20, 32).([]float32)
+
+	cases := []struct {
+		name string
+		// views returns the operands of the op, given tensors of shape (3, 4) with backings A and B
+		views func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor)
+	}{
+		{"transposed", func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor) {
+			if err := a.T(); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.T(); err != nil {
+				t.Fatal(err)
+			}
+			return a, b
+		}},
+		{"transposed and contiguous", func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor) {
+			if err := a.T(); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Reshape(4, 3); err != nil {
+				t.Fatal(err)
+			}
+			return a, b
+		}},
+		{"row slices", func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor) {
+			av, err := a.Slice(tensor.S(1, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(tensor.S(0, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return av, bv
+		}},
+		{"column slices", func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor) {
+			av, err := a.Slice(nil, tensor.S(1, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(nil, tensor.S(2, 4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return av, bv
+		}},
+		{"stepped slices", func(t *testing.T, a, b *tensor.Dense) (tensor.Tensor, tensor.Tensor) {
+			av, err := a.Slice(nil, tensor.S(0, 4, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(nil, tensor.S(1, 4, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return av, bv
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert.New(t)
+			e := newTestEngine(t)
+			a, b := c.views(t, engineTensor(t, e, backingA, 3, 4), engineTensor(t, e, backingB, 3, 4))
+			stdA, stdB := c.views(t, stdTensor(backingA, 3, 4), stdTensor(backingB, 3, 4))
+
+			sub, err := e.Sub(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := tensor.Sub(stdA, stdB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(rowMajorData(t, expected), readback(t, e, sub).Data())
+
+			div, err := e.DivScalar(a, float32(2), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected, err = tensor.Div(float32(2), stdA); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(allWithinRange(rowMajorData(t, expected), readback(t, e, div).Data().([]float32), closef32))
+
+			// in place, into a view
+			if _, err = e.Add(a, b, tensor.UseUnsafe()); err != nil {
+				t.Fatal(err)
+			}
+			if expected, err = tensor.Add(stdA, stdB); err != nil {
+				t.Fatal(err)
+			}
+			m, err := e.Materialize(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(rowMajorData(t, expected), readback(t, e, m).Data())
+		})
+	}
+}
+
+func TestEngine_ColMajor(t *testing.T) {
+	e := newTestEngine(t)
+	raw := []float32{1, 2, 3, 4, 5, 6}
+	a := engineColMajor(t, e, raw, 2, 3)
+	b := engineTensor(t, e, []float32{10, 20, 30, 40, 50, 60}, 2, 3)
+	c, err := e.Add(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := tensor.Add(stdColMajor(raw, 2, 3), stdTensor([]float32{10, 20, 30, 40, 50, 60}, 2, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rowMajorData(t, expected), readback(t, e, c).Data())
+	assert.Equal(t, []float32{11, 23, 35, 42, 54, 66}, readback(t, e, c).Data())
+}
+
+func TestEngine_Materialize(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestEngine(t)
+	a := engineTensor(t, e, tensor.Range(tensor.Float32, 0, 6).([]float32), 2, 3)
+	m, err := e.Materialize(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(m == a, "a row major tensor is already materialized")
+
+	if err = a.T(); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = e.Materialize(a); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(isRowMajor(m))
+	assert.Equal(tensor.Shape{3, 2}, m.Shape())
+	assert.Equal([]float32{0, 3, 1, 4, 2, 5}, readback(t, e, m).Data())
+}
+
+func TestEngine_StridedMatMul(t *testing.T) {
+	backingA := tensor.Range(tensor.Float32, 1, 25).([]float32)
+	backingB := tensor.Range(tensor.Float32, 2, 26).([]float32)
+
+	// each case returns a (2, 3) a and a (3, 2) b from tensors of shape (4, 6) with backings A and B
+	cases := []struct {
+		name  string
+		views func(t *testing.T, a, b *tensor.Dense) (*tensor.Dense, *tensor.Dense)
+	}{
+		{"sliced", func(t *testing.T, a, b *tensor.Dense) (*tensor.Dense, *tensor.Dense) {
+			av, err := a.Slice(tensor.S(1, 3), tensor.S(2, 5))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(tensor.S(0, 3), tensor.S(4, 6))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return av.(*tensor.Dense), bv.(*tensor.Dense)
+		}},
+		{"transposed", func(t *testing.T, a, b *tensor.Dense) (*tensor.Dense, *tensor.Dense) {
+			av, err := a.Slice(tensor.S(0, 3), tensor.S(0, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(tensor.S(1, 3), tensor.S(3, 6))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = av.(*tensor.Dense).T(); err != nil {
+				t.Fatal(err)
+			}
+			if err = bv.(*tensor.Dense).T(); err != nil {
+				t.Fatal(err)
+			}
+			return av.(*tensor.Dense), bv.(*tensor.Dense)
+		}},
+		{"strided along both axes", func(t *testing.T, a, b *tensor.Dense) (*tensor.Dense, *tensor.Dense) {
+			av, err := a.Slice(tensor.S(0, 4, 2), tensor.S(0, 6, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bv, err := b.Slice(tensor.S(0, 3), tensor.S(0, 4, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return av.(*tensor.Dense), bv.(*tensor.Dense)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestEngine(t)
+			a, b := c.views(t, engineTensor(t, e, backingA, 4, 6), engineTensor(t, e, backingB, 4, 6))
+			stdA, stdB := c.views(t, stdTensor(backingA, 4, 6), stdTensor(backingB, 4, 6))
+			// StdEng's MatMul reads views as if they were contiguous
+			expected, err := tensor.MatMul(stdA.Materialize(), stdB.Materialize())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// into a row major result
+			prealloc := engineTensor(t, e, make([]float32, 4), 2, 2)
+			if err = e.MatMul(a, b, prealloc); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, rowMajorData(t, expected), readback(t, e, prealloc).Data())
+
+			// into a transposed result
+			ct := engineTensor(t, e, make([]float32, 4), 2, 2)
+			if err = ct.T(); err != nil {
+				t.Fatal(err)
+			}
+			if err = e.MatMul(a, b, ct); err != nil {
+				t.Fatal(err)
+			}
+			m, err := e.Materialize(ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, rowMajorData(t, expected), readback(t, e, m).Data())
+
+			// into a column of a larger result
+			big := engineTensor(t, e, make([]float32, 12), 2, 6)
+			cv, err := big.Slice(nil, tensor.S(0, 4, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = e.MatMul(a, b, cv); err != nil {
+				t.Fatal(err)
+			}
+			exp := rowMajorData(t, expected)
+			assert.Equal(t, []float32{exp[0], 0, exp[1], 0, 0, 0, exp[2], 0, exp[3], 0, 0, 0}, readback(t, e, big).Data())
+		})
+	}
+}
+
+func TestEngine_StridedSoftMax(t *testing.T) {
+	e := newTestEngine(t)
+	backing := []float32{1, 2, 3, 4, 5, 6}
+	x := engineTensor(t, e, backing, 2, 3)
+	if err := x.T(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := e.SoftMax(x, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdX := stdTensor(backing, 2, 3)
+	if err = stdX.T(); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := tensor.SoftMax(stdX.Materialize(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, allWithinRange(rowMajorData(t, expected), readback(t, e, out).Data().([]float32), closef32))
+}
+
+func TestEngine_WorksWith(t *testing.T) {
+	e := newTestEngine(t)
+	assert.True(t, e.WorksWith(tensor.MakeDataOrder()))
+	assert.True(t, e.WorksWith(tensor.MakeDataOrder(tensor.ColMajor)))
+	assert.True(t, e.WorksWith(tensor.MakeDataOrder(tensor.NonContiguous, tensor.Transposed)))
+	assert.False(t, e.WorksWith(tensor.DataOrder(1<<7)))
+}
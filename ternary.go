@@ -0,0 +1,119 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Where selects the elements of a where cond is true, and those of b elsewhere, broadcasting the three against each other.
+// cond is either a Bool tensor, or a tensor of the Dtype of a and b, in which case elements that aren't 0 are true.
+// If the op is unsafe, the result is written into a.
+//
+// Where has no tensor.StdEng equivalent, and fails for Float64s.
+func (e *Engine) Where(cond, a, b tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.Where(cond, a, b) })
+	}
+	if err = e.checkValidDtype(a, b); err != nil {
+		return nil, errors.Wrap(err, "Where()")
+	}
+	if cond.Dtype() != tensor.Bool && cond.Dtype() != a.Dtype() {
+		return nil, errors.Errorf("Where(): Expected cond to be of Bools or of %v. Got %v instead", a.Dtype(), cond.Dtype())
+	}
+	shape, err := broadcastShapes(cond.Shape(), a.Shape(), b.Shape())
+	if err != nil {
+		return nil, errors.Wrap(err, "Where()")
+	}
+	kernel := func(strided bool) (string, error) { return whereName(strided, cond.Dtype(), a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, "Where()")
+	}
+	if retVal, err = e.prepResult(a, shape, opts...); err != nil {
+		return nil, errors.Wrap(err, "Where()")
+	}
+	if err = e.runElementwise(kernel, shape, retVal, cond, a, b); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, "Where()")
+	}
+	return retVal, nil
+}
+
+// Clamp clamps the elements of a between min and max. Elements that are NaNs stay NaNs.
+//
+// min and max are either both scalars of the Dtype of a, as with tensor.Clamp,
+// or both tensors of it, which are broadcast against a, bounding each element of a by their own.
+// If the op is unsafe, the result is written into a.
+func (e *Engine) Clamp(a tensor.Tensor, min, max interface{}, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = std.Clamp(a, min, max, opts...)
+		return err
+	}, a); ok {
+		return retVal, errors.Wrap(err, "Clamp()")
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.Clamp(a, min, max) })
+	}
+	lo, loTensor := min.(tensor.Tensor)
+	hi, hiTensor := max.(tensor.Tensor)
+	switch {
+	case loTensor && hiTensor:
+		retVal, err = e.clampBetween(a, lo, hi, opts...)
+	case !loTensor && !hiTensor:
+		retVal, err = e.clampScalar(a, min, max, opts...)
+	default:
+		err = errors.Errorf("Expected min and max to both be scalars or both be tensors. Got %T and %T", min, max)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Clamp()")
+	}
+	return retVal, nil
+}
+
+// clampBetween clamps a between the tensors lo and hi, broadcasting the three against each other.
+func (e *Engine) clampBetween(a, lo, hi tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	if err := e.checkValidDtype(a, lo, hi); err != nil {
+		return nil, err
+	}
+	kernel := func(strided bool) (string, error) { return stridedName(clampOp.Name, strided, a.Dtype()) }
+	if err := e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, err
+	}
+	shape, err := broadcastShapes(a.Shape(), lo.Shape(), hi.Shape())
+	if err != nil {
+		return nil, err
+	}
+	retVal, err := e.prepResult(a, shape, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.runElementwise(kernel, shape, retVal, a, lo, hi); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, err
+	}
+	return retVal, nil
+}
+
+// clampScalar clamps a between the scalars lo and hi.
+func (e *Engine) clampScalar(a tensor.Tensor, lo, hi interface{}, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	loBytes, err := e.checkValidScalar(a, lo)
+	if err != nil {
+		return nil, err
+	}
+	hiBytes, err := e.checkValidScalar(a, hi)
+	if err != nil {
+		return nil, err
+	}
+	kernel := func(strided bool) (string, error) { return stridedName(clampOp.Name+"_vs", strided, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, err
+	}
+	retVal, err := e.prepResult(a, a.Shape(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.runScalar(kernel, a, retVal, loBytes, hiBytes); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, err
+	}
+	return retVal, nil
+}
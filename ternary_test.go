@@ -0,0 +1,161 @@
+package magol
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_Where(t *testing.T) {
+	e := newTestEngine(t)
+	a := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	b := engineTensor(t, e, []float32{-1, -2, -3, -4, -5, -6}, 2, 3)
+
+	mask := engineCopy(t, e, tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]bool{true, false, true, false, false, true})))
+	got, err := e.Where(mask, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{2, 3}, got.Shape())
+	assert.Equal(t, []float32{1, -2, 3, -4, -5, 6}, readback(t, e, got).Data(), "Bool condition")
+
+	// elements of the condition that aren't 0 are true, including NaNs
+	cond := engineTensor(t, e, []float32{0, 0.5, float32(math.NaN()), -1, 0, float32(math.Copysign(0, -1))}, 2, 3)
+	if got, err = e.Where(cond, a, b); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{-1, 2, 3, 4, -5, -6}, readback(t, e, got).Data(), "Float32 condition")
+
+	// a masked fill: a row of conditions and a single fill value are broadcast
+	row := engineCopy(t, e, tensor.New(tensor.WithShape(3), tensor.WithBacking([]bool{false, true, false})))
+	fill := engineTensor(t, e, []float32{0}, 1)
+	if got, err = e.Where(row, fill, a); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{1, 0, 3, 4, 0, 6}, readback(t, e, got).Data(), "broadcast")
+
+	// a column of conditions selects whole rows, of a transposed view
+	col := engineCopy(t, e, tensor.New(tensor.WithShape(3, 1), tensor.WithBacking([]bool{true, false, true})))
+	at := engineTensor(t, e, []float32{1, 2, 3, 4, 5, 6}, 2, 3)
+	if err = at.T(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = e.Where(col, at, fill); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tensor.Shape{3, 2}, got.Shape())
+	assert.Equal(t, []float32{1, 4, 0, 0, 3, 6}, readback(t, e, got).Data(), "transposed")
+
+	// unsafe, the result goes into a
+	if got, err = e.Where(mask, a, b, tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, got == a, "unsafe ops should write into a")
+	assert.Equal(t, []float32{1, -2, 3, -4, -5, 6}, readback(t, e, a).Data(), "unsafe")
+
+	// the dtypes of the selected elements
+	i := engineCopy(t, e, tensor.New(tensor.WithBacking([]int8{1, 2, 3})))
+	j := engineCopy(t, e, tensor.New(tensor.WithBacking([]int8{-1, -2, -3})))
+	ic := engineCopy(t, e, tensor.New(tensor.WithBacking([]int8{0, 7, 0})))
+	if got, err = e.Where(ic, i, j); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int8{-1, 2, -3}, readback(t, e, got).Data(), "Int8")
+	p := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, true, false})))
+	q := engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{false, false, false})))
+	if got, err = e.Where(row, p, q); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []bool{false, true, false}, readback(t, e, got).Data(), "Bool")
+	h, k := engineHalfTensor(t, e, BFloat16, []float32{1, 2, 3}, 3), engineHalfTensor(t, e, BFloat16, []float32{4, 5, 6}, 3)
+	if got, err = e.Where(row, h, k); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{4, 2, 6}, readbackFloats(t, e, got), "BFloat16")
+
+	_, err = e.Where(ic, a, b)
+	assert.Error(t, err, "an Int8 condition for Float32s")
+	_, err = e.Where(mask, a, i)
+	assert.Error(t, err, "mixing dtypes")
+	_, err = e.Where(engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true, false}))), a, b)
+	assert.Error(t, err, "shapes that don't broadcast")
+}
+
+func TestEngine_Clamp(t *testing.T) {
+	backing := []float32{-3, -0.5, 0, float32(math.NaN()), 2, 7}
+	want, err := tensor.StdEng{}.Clamp(tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float32(nil), backing...))), float32(-1), float32(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestEngine(t)
+	a := engineTensor(t, e, backing, 2, 3)
+	got, err := tensor.Clamp(a, float32(-1), float32(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, withoutNaNs(want.Data().([]float32)), readbackNaNs(t, e, got), "the results should be those of StdEng, NaNs included")
+
+	// a transposed view uses the strided kernel
+	at := engineTensor(t, e, backing, 2, 3)
+	if err = at.T(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = e.Clamp(at, float32(-1), float32(2)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{-1, -999, -0.5, 2, 0, 2}, readbackNaNs(t, e, got), "transposed")
+
+	// the bounds of each column, broadcast along the rows
+	lo := engineTensor(t, e, []float32{-5, 0, 1}, 3)
+	hi := engineTensor(t, e, []float32{-4, 1, 3}, 1, 3)
+	if got, err = e.Clamp(a, lo, hi); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float32{-4, 0, 1, -999, 1, 3}, readbackNaNs(t, e, got), "tensor bounds")
+
+	// gradient clipping, in place
+	if got, err = e.Clamp(a, float32(-1), float32(1), tensor.UseUnsafe()); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, got == a, "unsafe ops should write into a")
+
+	i := engineCopy(t, e, tensor.New(tensor.WithBacking([]int32{-10, 5, 10})))
+	if got, err = e.Clamp(i, int32(0), int32(8)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int32{0, 5, 8}, readback(t, e, got).Data(), "Int32")
+
+	_, err = e.Clamp(a, float32(0), hi)
+	assert.Error(t, err, "a scalar and a tensor bound")
+	_, err = e.Clamp(a, 0.0, 1.0)
+	assert.Error(t, err, "float64 bounds")
+	_, err = e.Clamp(engineCopy(t, e, tensor.New(tensor.WithBacking([]bool{true}))), false, true)
+	assert.Error(t, err, "Bools")
+
+	// Float64s are clamped by StdEng
+	e64 := newTestEngine(t, WithFloat64OnHost())
+	x := engineFloat64s(t, e64, []float64{-2, 0.5, 3}, 3)
+	if got, err = e64.Clamp(x, float64(0), float64(1)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{0, 0.5, 1}, readback(t, e64, got).Data(), "Float64")
+}
+
+// readbackNaNs reads back the Float32s of x, with NaNs replaced by -999 so that they can be compared.
+func readbackNaNs(t *testing.T, e *Engine, x tensor.Tensor) []float32 {
+	return withoutNaNs(readback(t, e, x).Data().([]float32))
+}
+
+// withoutNaNs returns a copy of fs with NaNs replaced by -999.
+func withoutNaNs(fs []float32) []float32 {
+	retVal := append([]float32(nil), fs...)
+	for i, v := range retVal {
+		if v != v {
+			retVal[i] = -999
+		}
+	}
+	return retVal
+}
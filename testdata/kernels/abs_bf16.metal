@@ -0,0 +1,10 @@
+
+kernel void abs_bf16(
+    device const ushort* in,
+    device ushort* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(in[off.x]);
+    result[off.w] = float_to_bf16(abs(a));
+}
@@ -0,0 +1,10 @@
+
+kernel void abs_i8(
+    device const char* in,
+    device char* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    result[off.w] = char(abs(a));
+}
@@ -0,0 +1,11 @@
+
+kernel void abs_strided_i8(
+    device const char* in,
+    device char* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    result[off.w] = char(abs(a));
+}
@@ -0,0 +1,12 @@
+
+kernel void add_i32(
+    device const int* inA,
+    device const int* inB,
+    device int* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = a + b;
+}
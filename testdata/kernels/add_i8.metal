@@ -0,0 +1,12 @@
+
+kernel void add_i8(
+    device const char* inA,
+    device const char* inB,
+    device char* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = char(a + b);
+}
@@ -0,0 +1,12 @@
+
+kernel void add_sv_f16(
+    device const half* in,
+    device half* result,
+    constant half& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(scalar);
+    float b = float(in[off.x]);
+    result[off.w] = half(a + b);
+}
@@ -0,0 +1,12 @@
+
+kernel void add_u8(
+    device const uchar* inA,
+    device const uchar* inB,
+    device uchar* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = uchar(a + b);
+}
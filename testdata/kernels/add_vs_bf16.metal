@@ -0,0 +1,12 @@
+
+kernel void add_vs_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant ushort& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(in[off.x]);
+    float b = bf16_to_float(scalar);
+    result[off.w] = float_to_bf16(a + b);
+}
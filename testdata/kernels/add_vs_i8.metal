@@ -0,0 +1,12 @@
+
+kernel void add_vs_i8(
+    device const char* in,
+    device char* result,
+    constant char& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = char(a + b);
+}
@@ -0,0 +1,13 @@
+
+kernel void add_vs_strided_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = a + b;
+}
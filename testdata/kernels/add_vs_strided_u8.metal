@@ -0,0 +1,13 @@
+
+kernel void add_vs_strided_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant uchar& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = uchar(a + b);
+}
@@ -0,0 +1,56 @@
+
+// argmax_i32_bf16_better reports whether a, at index i, is a better candidate than b, at index j.
+static bool argmax_i32_bf16_better(float a, uint i, float b, uint j) {
+    if (i == NO_INDEX) {
+        return false;
+    }
+    if (j == NO_INDEX) {
+        return true;
+    }
+    if (isnan(a) != isnan(b)) {
+        return isnan(a);
+    }
+    if (isnan(a) || a == b) {
+        return i < j;
+    }
+    return a > b;
+}
+
+kernel void argmax_i32_bf16(
+    device const ushort* in,
+    device int* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    threadgroup float values[REDUCE_THREADS];
+    threadgroup uint indices[REDUCE_THREADS];
+    uint4 base = offsetsOf(outer, group);
+    uint n = 1;
+    for (uint d = 0; d < inner.dims; d++) {
+        n *= inner.shape[d];
+    }
+    float best = 0;
+    uint at = NO_INDEX;
+    for (uint i = tid; i < n; i += REDUCE_THREADS) {
+        float v = bf16_to_float(in[base.x + offsetsOf(inner, i).x]);
+        if (argmax_i32_bf16_better(v, i, best, at)) {
+            best = v;
+            at = i;
+        }
+    }
+    values[tid] = best;
+    indices[tid] = at;
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    for (uint s = REDUCE_THREADS / 2; s > 0; s >>= 1) {
+        if (tid < s && argmax_i32_bf16_better(values[tid + s], indices[tid + s], values[tid], indices[tid])) {
+            values[tid] = values[tid + s];
+            indices[tid] = indices[tid + s];
+        }
+        threadgroup_barrier(mem_flags::mem_threadgroup);
+    }
+    if (tid == 0) {
+        result[base.w] = indices[0] == NO_INDEX ? -1 : int(indices[0]);
+    }
+}
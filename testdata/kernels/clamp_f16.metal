@@ -0,0 +1,14 @@
+
+kernel void clamp_f16(
+    device const half* inA,
+    device const half* inB,
+    device const half* inC,
+    device half* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    float c = float(inC[off.z]);
+    result[off.w] = half(a < b ? b : (a > c ? c : a));
+}
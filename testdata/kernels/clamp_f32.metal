@@ -0,0 +1,14 @@
+
+kernel void clamp_f32(
+    device const float* inA,
+    device const float* inB,
+    device const float* inC,
+    device float* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = inA[off.x];
+    float b = inB[off.y];
+    float c = inC[off.z];
+    result[off.w] = a < b ? b : (a > c ? c : a);
+}
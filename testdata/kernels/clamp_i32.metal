@@ -0,0 +1,14 @@
+
+kernel void clamp_i32(
+    device const int* inA,
+    device const int* inB,
+    device const int* inC,
+    device int* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = a < b ? b : (a > c ? c : a);
+}
@@ -0,0 +1,15 @@
+
+kernel void clamp_strided_i8(
+    device const char* inA,
+    device const char* inB,
+    device const char* inC,
+    device char* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = char(a < b ? b : (a > c ? c : a));
+}
@@ -0,0 +1,14 @@
+
+kernel void clamp_u8(
+    device const uchar* inA,
+    device const uchar* inB,
+    device const uchar* inC,
+    device uchar* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = uchar(a < b ? b : (a > c ? c : a));
+}
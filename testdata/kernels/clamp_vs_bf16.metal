@@ -0,0 +1,14 @@
+
+kernel void clamp_vs_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant ushort& scalarB,
+    constant ushort& scalarC,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(in[off.x]);
+    float b = bf16_to_float(scalarB);
+    float c = bf16_to_float(scalarC);
+    result[off.w] = float_to_bf16(a < b ? b : (a > c ? c : a));
+}
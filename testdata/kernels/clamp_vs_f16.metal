@@ -0,0 +1,14 @@
+
+kernel void clamp_vs_f16(
+    device const half* in,
+    device half* result,
+    constant half& scalarB,
+    constant half& scalarC,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(in[off.x]);
+    float b = float(scalarB);
+    float c = float(scalarC);
+    result[off.w] = half(a < b ? b : (a > c ? c : a));
+}
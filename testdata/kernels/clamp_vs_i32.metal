@@ -0,0 +1,14 @@
+
+kernel void clamp_vs_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalarB,
+    constant int& scalarC,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalarB;
+    int c = scalarC;
+    result[off.w] = a < b ? b : (a > c ? c : a);
+}
@@ -0,0 +1,14 @@
+
+kernel void clamp_vs_i8(
+    device const char* in,
+    device char* result,
+    constant char& scalarB,
+    constant char& scalarC,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalarB;
+    int c = scalarC;
+    result[off.w] = char(a < b ? b : (a > c ? c : a));
+}
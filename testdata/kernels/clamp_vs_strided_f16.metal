@@ -0,0 +1,15 @@
+
+kernel void clamp_vs_strided_f16(
+    device const half* in,
+    device half* result,
+    constant half& scalarB,
+    constant half& scalarC,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(in[off.x]);
+    float b = float(scalarB);
+    float c = float(scalarC);
+    result[off.w] = half(a < b ? b : (a > c ? c : a));
+}
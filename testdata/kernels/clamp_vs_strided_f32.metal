@@ -0,0 +1,15 @@
+
+kernel void clamp_vs_strided_f32(
+    device const float* in,
+    device float* result,
+    constant float& scalarB,
+    constant float& scalarC,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = in[off.x];
+    float b = scalarB;
+    float c = scalarC;
+    result[off.w] = a < b ? b : (a > c ? c : a);
+}
@@ -0,0 +1,15 @@
+
+kernel void clamp_vs_strided_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalarB,
+    constant int& scalarC,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalarB;
+    int c = scalarC;
+    result[off.w] = a < b ? b : (a > c ? c : a);
+}
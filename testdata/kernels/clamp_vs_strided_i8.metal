@@ -0,0 +1,15 @@
+
+kernel void clamp_vs_strided_i8(
+    device const char* in,
+    device char* result,
+    constant char& scalarB,
+    constant char& scalarC,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalarB;
+    int c = scalarC;
+    result[off.w] = char(a < b ? b : (a > c ? c : a));
+}
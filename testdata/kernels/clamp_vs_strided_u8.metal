@@ -0,0 +1,15 @@
+
+kernel void clamp_vs_strided_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant uchar& scalarB,
+    constant uchar& scalarC,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalarB;
+    int c = scalarC;
+    result[off.w] = uchar(a < b ? b : (a > c ? c : a));
+}
@@ -0,0 +1,10 @@
+
+kernel void copy_bool(
+    device const bool* in,
+    device bool* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = in[off.x];
+    result[off.w] = a;
+}
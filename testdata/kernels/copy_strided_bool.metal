@@ -0,0 +1,11 @@
+
+kernel void copy_strided_bool(
+    device const bool* in,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = in[off.x];
+    result[off.w] = a;
+}
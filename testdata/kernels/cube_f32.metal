@@ -0,0 +1,10 @@
+
+kernel void cube_f32(
+    device const float* in,
+    device float* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = in[off.x];
+    result[off.w] = a * a * a;
+}
@@ -0,0 +1,11 @@
+
+kernel void cube_strided_f16(
+    device const half* in,
+    device half* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(in[off.x]);
+    result[off.w] = half(a * a * a);
+}
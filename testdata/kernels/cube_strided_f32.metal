@@ -0,0 +1,11 @@
+
+kernel void cube_strided_f32(
+    device const float* in,
+    device float* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = in[off.x];
+    result[off.w] = a * a * a;
+}
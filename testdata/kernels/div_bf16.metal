@@ -0,0 +1,12 @@
+
+kernel void div_bf16(
+    device const ushort* inA,
+    device const ushort* inB,
+    device ushort* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(inA[off.x]);
+    float b = bf16_to_float(inB[off.y]);
+    result[off.w] = float_to_bf16(a / b);
+}
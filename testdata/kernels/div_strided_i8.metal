@@ -0,0 +1,13 @@
+
+kernel void div_strided_i8(
+    device const char* inA,
+    device const char* inB,
+    device char* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = char(b == 0 ? 0 : a / b);
+}
@@ -0,0 +1,13 @@
+
+kernel void div_strided_u8(
+    device const uchar* inA,
+    device const uchar* inB,
+    device uchar* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = uchar(b == 0 ? 0 : a / b);
+}
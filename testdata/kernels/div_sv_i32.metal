@@ -0,0 +1,12 @@
+
+kernel void div_sv_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = b == 0 ? 0 : a / b;
+}
@@ -0,0 +1,13 @@
+
+kernel void div_sv_strided_f16(
+    device const half* in,
+    device half* result,
+    constant half& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(scalar);
+    float b = float(in[off.x]);
+    result[off.w] = half(a / b);
+}
@@ -0,0 +1,13 @@
+
+kernel void div_sv_strided_i8(
+    device const char* in,
+    device char* result,
+    constant char& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = char(b == 0 ? 0 : a / b);
+}
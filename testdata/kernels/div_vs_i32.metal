@@ -0,0 +1,12 @@
+
+kernel void div_vs_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = b == 0 ? 0 : a / b;
+}
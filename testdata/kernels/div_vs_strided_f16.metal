@@ -0,0 +1,13 @@
+
+kernel void div_vs_strided_f16(
+    device const half* in,
+    device half* result,
+    constant half& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(in[off.x]);
+    float b = float(scalar);
+    result[off.w] = half(a / b);
+}
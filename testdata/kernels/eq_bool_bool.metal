@@ -0,0 +1,12 @@
+
+kernel void eq_bool_bool(
+    device const bool* inA,
+    device const bool* inB,
+    device bool* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = inA[off.x];
+    bool b = inB[off.y];
+    result[off.w] = a == b;
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_strided_bool_f16(
+    device const half* inA,
+    device const half* inB,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    result[off.w] = a == b;
+}
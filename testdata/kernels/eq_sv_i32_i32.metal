@@ -0,0 +1,12 @@
+
+kernel void eq_sv_i32_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = int(a == b);
+}
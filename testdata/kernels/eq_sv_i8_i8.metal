@@ -0,0 +1,12 @@
+
+kernel void eq_sv_i8_i8(
+    device const char* in,
+    device char* result,
+    constant char& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = char(int(a == b));
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_sv_strided_bf16_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant ushort& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = bf16_to_float(scalar);
+    float b = bf16_to_float(in[off.x]);
+    result[off.w] = float_to_bf16(float(a == b));
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_sv_strided_bool_bool(
+    device const bool* in,
+    device bool* result,
+    constant bool& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = scalar;
+    bool b = in[off.x];
+    result[off.w] = a == b;
+}
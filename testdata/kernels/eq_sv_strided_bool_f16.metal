@@ -0,0 +1,13 @@
+
+kernel void eq_sv_strided_bool_f16(
+    device const half* in,
+    device bool* result,
+    constant half& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(scalar);
+    float b = float(in[off.x]);
+    result[off.w] = a == b;
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_sv_strided_i32_i32(
+    device const int* in,
+    device int* result,
+    constant int& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = int(a == b);
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_sv_strided_u8_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant uchar& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = uchar(int(a == b));
+}
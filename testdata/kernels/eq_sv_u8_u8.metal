@@ -0,0 +1,12 @@
+
+kernel void eq_sv_u8_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant uchar& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = uchar(int(a == b));
+}
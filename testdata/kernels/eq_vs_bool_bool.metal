@@ -0,0 +1,12 @@
+
+kernel void eq_vs_bool_bool(
+    device const bool* in,
+    device bool* result,
+    constant bool& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = in[off.x];
+    bool b = scalar;
+    result[off.w] = a == b;
+}
@@ -0,0 +1,12 @@
+
+kernel void eq_vs_f32_f32(
+    device const float* in,
+    device float* result,
+    constant float& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = in[off.x];
+    float b = scalar;
+    result[off.w] = float(a == b);
+}
@@ -0,0 +1,13 @@
+
+kernel void eq_vs_strided_bool_bool(
+    device const bool* in,
+    device bool* result,
+    constant bool& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = in[off.x];
+    bool b = scalar;
+    result[off.w] = a == b;
+}
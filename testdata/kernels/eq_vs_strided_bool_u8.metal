@@ -0,0 +1,13 @@
+
+kernel void eq_vs_strided_bool_u8(
+    device const uchar* in,
+    device bool* result,
+    constant uchar& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = a == b;
+}
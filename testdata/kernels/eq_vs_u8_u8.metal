@@ -0,0 +1,12 @@
+
+kernel void eq_vs_u8_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant uchar& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = uchar(int(a == b));
+}
@@ -0,0 +1,10 @@
+
+kernel void exp_f16(
+    device const half* in,
+    device half* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(in[off.x]);
+    result[off.w] = half(exp(a));
+}
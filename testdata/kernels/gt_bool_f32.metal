@@ -0,0 +1,12 @@
+
+kernel void gt_bool_f32(
+    device const float* inA,
+    device const float* inB,
+    device bool* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = inA[off.x];
+    float b = inB[off.y];
+    result[off.w] = a > b;
+}
@@ -0,0 +1,13 @@
+
+kernel void gt_strided_f16_f16(
+    device const half* inA,
+    device const half* inB,
+    device half* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    result[off.w] = half(float(a > b));
+}
@@ -0,0 +1,13 @@
+
+kernel void gt_strided_i32_i32(
+    device const int* inA,
+    device const int* inB,
+    device int* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = int(a > b);
+}
@@ -0,0 +1,13 @@
+
+kernel void gt_strided_i8_i8(
+    device const char* inA,
+    device const char* inB,
+    device char* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = char(int(a > b));
+}
@@ -0,0 +1,12 @@
+
+kernel void gt_sv_bool_f32(
+    device const float* in,
+    device bool* result,
+    constant float& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = scalar;
+    float b = in[off.x];
+    result[off.w] = a > b;
+}
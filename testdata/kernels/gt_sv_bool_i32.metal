@@ -0,0 +1,12 @@
+
+kernel void gt_sv_bool_i32(
+    device const int* in,
+    device bool* result,
+    constant int& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = a > b;
+}
@@ -0,0 +1,13 @@
+
+kernel void gt_sv_strided_bool_bf16(
+    device const ushort* in,
+    device bool* result,
+    constant ushort& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = bf16_to_float(scalar);
+    float b = bf16_to_float(in[off.x]);
+    result[off.w] = a > b;
+}
@@ -0,0 +1,13 @@
+
+kernel void gt_sv_strided_bool_f32(
+    device const float* in,
+    device bool* result,
+    constant float& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = scalar;
+    float b = in[off.x];
+    result[off.w] = a > b;
+}
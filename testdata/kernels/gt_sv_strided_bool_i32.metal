@@ -0,0 +1,13 @@
+
+kernel void gt_sv_strided_bool_i32(
+    device const int* in,
+    device bool* result,
+    constant int& scalar,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = scalar;
+    int b = in[off.x];
+    result[off.w] = a > b;
+}
@@ -0,0 +1,12 @@
+
+kernel void gt_vs_bool_bf16(
+    device const ushort* in,
+    device bool* result,
+    constant ushort& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(in[off.x]);
+    float b = bf16_to_float(scalar);
+    result[off.w] = a > b;
+}
@@ -0,0 +1,12 @@
+
+kernel void gt_vs_bool_f16(
+    device const half* in,
+    device bool* result,
+    constant half& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(in[off.x]);
+    float b = float(scalar);
+    result[off.w] = a > b;
+}
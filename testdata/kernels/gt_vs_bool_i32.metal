@@ -0,0 +1,12 @@
+
+kernel void gt_vs_bool_i32(
+    device const int* in,
+    device bool* result,
+    constant int& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    int b = scalar;
+    result[off.w] = a > b;
+}
@@ -0,0 +1,12 @@
+
+kernel void gte_f16_f16(
+    device const half* inA,
+    device const half* inB,
+    device half* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    result[off.w] = half(float(a >= b));
+}
@@ -0,0 +1,13 @@
+
+kernel void gte_strided_bool_f32(
+    device const float* inA,
+    device const float* inB,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = inA[off.x];
+    float b = inB[off.y];
+    result[off.w] = a >= b;
+}
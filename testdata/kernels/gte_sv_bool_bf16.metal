@@ -0,0 +1,12 @@
+
+kernel void gte_sv_bool_bf16(
+    device const ushort* in,
+    device bool* result,
+    constant ushort& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = bf16_to_float(scalar);
+    float b = bf16_to_float(in[off.x]);
+    result[off.w] = a >= b;
+}
@@ -0,0 +1,11 @@
+
+kernel void log_strided_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = bf16_to_float(in[off.x]);
+    result[off.w] = float_to_bf16(log(a));
+}
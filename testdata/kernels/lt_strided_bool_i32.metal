@@ -0,0 +1,13 @@
+
+kernel void lt_strided_bool_i32(
+    device const int* inA,
+    device const int* inB,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    result[off.w] = a < b;
+}
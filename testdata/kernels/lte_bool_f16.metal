@@ -0,0 +1,12 @@
+
+kernel void lte_bool_f16(
+    device const half* inA,
+    device const half* inB,
+    device bool* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    result[off.w] = a <= b;
+}
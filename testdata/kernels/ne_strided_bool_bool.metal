@@ -0,0 +1,13 @@
+
+kernel void ne_strided_bool_bool(
+    device const bool* inA,
+    device const bool* inB,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = inA[off.x];
+    bool b = inB[off.y];
+    result[off.w] = a != b;
+}
@@ -0,0 +1,12 @@
+
+kernel void ne_sv_bool_bool(
+    device const bool* in,
+    device bool* result,
+    constant bool& scalar,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = scalar;
+    bool b = in[off.x];
+    result[off.w] = a != b;
+}
@@ -0,0 +1,10 @@
+
+kernel void neg_i32(
+    device const int* in,
+    device int* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    result[off.w] = -a;
+}
@@ -0,0 +1,11 @@
+
+kernel void neg_strided_i32(
+    device const int* in,
+    device int* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    result[off.w] = -a;
+}
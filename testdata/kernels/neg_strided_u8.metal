@@ -0,0 +1,11 @@
+
+kernel void neg_strided_u8(
+    device const uchar* in,
+    device uchar* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = in[off.x];
+    result[off.w] = uchar(-a);
+}
@@ -0,0 +1,10 @@
+
+kernel void neg_u8(
+    device const uchar* in,
+    device uchar* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    int a = in[off.x];
+    result[off.w] = uchar(-a);
+}
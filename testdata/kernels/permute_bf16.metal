@@ -0,0 +1,32 @@
+
+kernel void permute_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    // the padding column keeps the threads reading a column of the tile out of each other's banks
+    threadgroup ushort tile[PERMUTE_TILE][PERMUTE_TILE + 1];
+    uint rows = inner.shape[0];
+    uint cols = inner.shape[1];
+    uint tilesR = (rows + PERMUTE_TILE - 1) / PERMUTE_TILE;
+    uint tilesC = (cols + PERMUTE_TILE - 1) / PERMUTE_TILE;
+    uint4 base = offsetsOf(outer, group / (tilesR * tilesC));
+    uint r0 = group % (tilesR * tilesC) / tilesC * PERMUTE_TILE;
+    uint c0 = group % tilesC * PERMUTE_TILE;
+    uint tx = tid % PERMUTE_TILE;
+    uint ty = tid / PERMUTE_TILE;
+    uint r = r0 + tx;
+    uint c = c0 + ty;
+    if (r < rows && c < cols) {
+        tile[ty][tx] = in[base.x + r * inner.strides[0][0] + c * inner.strides[0][1]];
+    }
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    r = r0 + ty;
+    c = c0 + tx;
+    if (r < rows && c < cols) {
+        result[base.w + r * inner.strides[MAX_OPERANDS][0] + c * inner.strides[MAX_OPERANDS][1]] = tile[tx][ty];
+    }
+}
@@ -0,0 +1,35 @@
+
+kernel void reduce_max_bf16(
+    device const ushort* in,
+    device ushort* result,
+    constant StridedLayout& outer,
+    constant StridedLayout& inner,
+    uint group [[threadgroup_position_in_grid]],
+    uint tid [[thread_position_in_threadgroup]])
+{
+    threadgroup float partial[REDUCE_THREADS];
+    uint4 base = offsetsOf(outer, group);
+    uint n = 1;
+    for (uint d = 0; d < inner.dims; d++) {
+        n *= inner.shape[d];
+    }
+    float acc = -INFINITY;
+    for (uint i = tid; i < n; i += REDUCE_THREADS) {
+        float a = acc;
+        float b = bf16_to_float(in[base.x + offsetsOf(inner, i).x]);
+        acc = max(a, b);
+    }
+    partial[tid] = acc;
+    threadgroup_barrier(mem_flags::mem_threadgroup);
+    for (uint s = REDUCE_THREADS / 2; s > 0; s >>= 1) {
+        if (tid < s) {
+            float a = partial[tid];
+            float b = partial[tid + s];
+            partial[tid] = max(a, b);
+        }
+        threadgroup_barrier(mem_flags::mem_threadgroup);
+    }
+    if (tid == 0) {
+        result[base.w] = float_to_bf16(partial[0]);
+    }
+}
@@ -0,0 +1,14 @@
+
+kernel void where_bool_bf16(
+    device const bool* inA,
+    device const ushort* inB,
+    device const ushort* inC,
+    device ushort* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = inA[off.x];
+    float b = bf16_to_float(inB[off.y]);
+    float c = bf16_to_float(inC[off.z]);
+    result[off.w] = float_to_bf16(a ? b : c);
+}
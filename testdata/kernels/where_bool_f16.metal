@@ -0,0 +1,14 @@
+
+kernel void where_bool_f16(
+    device const bool* inA,
+    device const half* inB,
+    device const half* inC,
+    device half* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = inA[off.x];
+    float b = float(inB[off.y]);
+    float c = float(inC[off.z]);
+    result[off.w] = half(a ? b : c);
+}
@@ -0,0 +1,14 @@
+
+kernel void where_bool_i32(
+    device const bool* inA,
+    device const int* inB,
+    device const int* inC,
+    device int* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = a ? b : c;
+}
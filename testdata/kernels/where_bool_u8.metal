@@ -0,0 +1,14 @@
+
+kernel void where_bool_u8(
+    device const bool* inA,
+    device const uchar* inB,
+    device const uchar* inC,
+    device uchar* result,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = index;
+    bool a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = uchar(a ? b : c);
+}
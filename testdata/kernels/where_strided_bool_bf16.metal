@@ -0,0 +1,15 @@
+
+kernel void where_strided_bool_bf16(
+    device const bool* inA,
+    device const ushort* inB,
+    device const ushort* inC,
+    device ushort* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = inA[off.x];
+    float b = bf16_to_float(inB[off.y]);
+    float c = bf16_to_float(inC[off.z]);
+    result[off.w] = float_to_bf16(a ? b : c);
+}
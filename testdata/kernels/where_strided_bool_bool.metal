@@ -0,0 +1,15 @@
+
+kernel void where_strided_bool_bool(
+    device const bool* inA,
+    device const bool* inB,
+    device const bool* inC,
+    device bool* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    bool a = inA[off.x];
+    bool b = inB[off.y];
+    bool c = inC[off.z];
+    result[off.w] = a ? b : c;
+}
@@ -0,0 +1,15 @@
+
+kernel void where_strided_f16_f16(
+    device const half* inA,
+    device const half* inB,
+    device const half* inC,
+    device half* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = float(inA[off.x]);
+    float b = float(inB[off.y]);
+    float c = float(inC[off.z]);
+    result[off.w] = half(a ? b : c);
+}
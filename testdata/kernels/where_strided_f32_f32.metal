@@ -0,0 +1,15 @@
+
+kernel void where_strided_f32_f32(
+    device const float* inA,
+    device const float* inB,
+    device const float* inC,
+    device float* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    float a = inA[off.x];
+    float b = inB[off.y];
+    float c = inC[off.z];
+    result[off.w] = a ? b : c;
+}
@@ -0,0 +1,15 @@
+
+kernel void where_strided_i32_i32(
+    device const int* inA,
+    device const int* inB,
+    device const int* inC,
+    device int* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = a ? b : c;
+}
@@ -0,0 +1,15 @@
+
+kernel void where_strided_u8_u8(
+    device const uchar* inA,
+    device const uchar* inB,
+    device const uchar* inC,
+    device uchar* result,
+    constant StridedLayout& l,
+    uint index [[thread_position_in_grid]])
+{
+    uint4 off = offsetsOf(l, index);
+    int a = inA[off.x];
+    int b = inB[off.y];
+    int c = inC[off.z];
+    result[off.w] = uchar(a ? b : c);
+}
@@ -1,16 +1,45 @@
+//go:build darwin
+// +build darwin
+
 package magol
 
-/*
-#cgo LDFLAGS: -framework Metal -framework CoreGraphics -framework Foundation -framework MetalPerformanceShaders
-#include <stdlib.h>
-#include <stdbool.h>
-#include <stdio.h>
-#include "magol.h"
-*/
-import "C"
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// The MPSDataTypes of MPSCoreTypes.h. MPS headers can't be included by cgo, which only speaks C.
+const (
+	mpsDataTypeFloatBit             = 0x10000000
+	mpsDataTypeSignedBit            = 0x20000000
+	mpsDataTypeAlternateEncodingBit = 0x80000000
+
+	mpsDataTypeFloat32  = mpsDataTypeFloatBit | 32
+	mpsDataTypeFloat16  = mpsDataTypeFloatBit | 16
+	mpsDataTypeBFloat16 = mpsDataTypeAlternateEncodingBit | mpsDataTypeFloat16
+	mpsDataTypeInt32    = mpsDataTypeSignedBit | 32
+	mpsDataTypeInt8     = mpsDataTypeSignedBit | 8
+	mpsDataTypeUInt8    = 8
+	mpsDataTypeBool     = mpsDataTypeAlternateEncodingBit | 8
+)
 
-// import "gorgonia.org/tensor"
+// typemap maps Dtypes to the MPSDataTypes of the matrices and vectors of MPS.
+// MPS only multiplies some of them: see linalgDtypes.
+var typemap = map[tensor.Dtype]uint32{
+	tensor.Float32: mpsDataTypeFloat32,
+	Float16:        mpsDataTypeFloat16,
+	BFloat16:       mpsDataTypeBFloat16,
+	tensor.Int32:   mpsDataTypeInt32,
+	tensor.Int8:    mpsDataTypeInt8,
+	tensor.Uint8:   mpsDataTypeUInt8,
+	tensor.Bool:    mpsDataTypeBool,
+}
 
-// var typemap = map[tensor.Dtype]C.MPSDataType{
-// 	tensor.Float32: C.MPSDataTypeFloat32,
-// }
+// mpsDataType returns the MPSDataType of dt.
+func mpsDataType(dt tensor.Dtype) (uint32, error) {
+	t, ok := typemap[dt]
+	if !ok {
+		return 0, errors.Errorf("No MPSDataType for %v", dt)
+	}
+	return t, nil
+}
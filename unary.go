@@ -0,0 +1,81 @@
+package magol
+
+import (
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+func (e *Engine) Exp(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Exp()", "exp", a, opts...)
+}
+
+func (e *Engine) Log(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Log()", "log", a, opts...)
+}
+
+func (e *Engine) Sqrt(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Sqrt()", "sqrt", a, opts...)
+}
+
+// InvSqrt computes 1/√a.
+func (e *Engine) InvSqrt(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("InvSqrt()", "rsqrt", a, opts...)
+}
+
+func (e *Engine) Tanh(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Tanh()", "tanh", a, opts...)
+}
+
+// Sigmoid computes the logistic function 1/(1+e⁻ᵃ).
+func (e *Engine) Sigmoid(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Sigmoid()", "sigmoid", a, opts...)
+}
+
+func (e *Engine) Abs(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Abs()", "abs", a, opts...)
+}
+
+func (e *Engine) Neg(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Neg()", "neg", a, opts...)
+}
+
+// Sign computes the sign of a: 1 for positive values, -1 for negative ones, and 0 for zeros and NaNs.
+func (e *Engine) Sign(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Sign()", "sign", a, opts...)
+}
+
+func (e *Engine) Square(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Square()", "square", a, opts...)
+}
+
+func (e *Engine) Cube(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	return e.unaryOp("Cube()", "cube", a, opts...)
+}
+
+// unaryOp runs the kernel of op elementwise over a. fn is the name of the calling method, for errors.
+func (e *Engine) unaryOp(fn, op string, a tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	if ok, err := e.onHost(func(std tensor.StdEng) (err error) {
+		retVal, err = stdUnaryOps[op](std, a, opts...)
+		return err
+	}, a); ok {
+		return retVal, errors.Wrap(err, fn)
+	}
+	if incr, ok := incrOf(opts); ok {
+		return e.accumulate(incr, func() (tensor.Tensor, error) { return e.unaryOp(fn, op, a) })
+	}
+	if err = e.checkValidDtype(a); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	kernel := func(strided bool) (string, error) { return stridedName(op, strided, a.Dtype()) }
+	if err = e.checkElementwise(kernel, a.Dtype()); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if retVal, err = e.prepResult(a, a.Shape(), opts...); err != nil {
+		return nil, errors.Wrap(err, fn)
+	}
+	if err = e.runScalar(kernel, a, retVal); err != nil {
+		e.releaseResult(retVal, a, opts)
+		return nil, errors.Wrap(err, fn)
+	}
+	return retVal, nil
+}
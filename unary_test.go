@@ -0,0 +1,109 @@
+package magol
+
+import (
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/stretchr/testify/assert"
+	"gorgonia.org/tensor"
+)
+
+func TestEngine_UnaryOps(t *testing.T) {
+	positive := []float32{0.5, 1, 2, 3.5, 4, 9}
+	mixed := []float32{-2.5, -1, 0, 0.5, 1, 3}
+	sigmoid := func(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+		retVal := a.Clone().(*tensor.Dense)
+		data := retVal.Data().([]float32)
+		for i, v := range data {
+			data[i] = 1 / (1 + math32.Exp(-v))
+		}
+		return retVal, nil
+	}
+	ops := []struct {
+		name    string
+		backing []float32
+		eng     func(e *Engine, a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+		std     func(a tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error)
+	}{
+		{"Exp", mixed, (*Engine).Exp, tensor.Exp},
+		{"Log", positive, (*Engine).Log, tensor.Log},
+		{"Sqrt", positive, (*Engine).Sqrt, tensor.Sqrt},
+		{"InvSqrt", positive, (*Engine).InvSqrt, tensor.InvSqrt},
+		{"Tanh", mixed, (*Engine).Tanh, tensor.Tanh},
+		{"Sigmoid", mixed, (*Engine).Sigmoid, sigmoid},
+		{"Abs", mixed, (*Engine).Abs, tensor.Abs},
+		{"Neg", mixed, (*Engine).Neg, tensor.Neg},
+		{"Sign", mixed, (*Engine).Sign, tensor.Sign},
+		{"Square", mixed, (*Engine).Square, tensor.Square},
+		{"Cube", mixed, (*Engine).Cube, tensor.Cube},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			assert := assert.New(t)
+			e := newTestEngine(t)
+			expected, err := op.std(stdTensor(op.backing, 2, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expected.Data().([]float32)
+
+			a := engineTensor(t, e, op.backing, 2, 3)
+			c, err := op.eng(e, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(allWithinRange(want, readback(t, e, c).Data().([]float32), closef32), "safe")
+			assert.Equal(op.backing, readback(t, e, a).Data(), "a should be untouched")
+
+			reuse := engineTensor(t, e, make([]float32, 6), 2, 3)
+			if c, err = op.eng(e, a, tensor.WithReuse(reuse)); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(c == reuse)
+			assert.True(allWithinRange(want, readback(t, e, reuse).Data().([]float32), closef32), "reuse")
+
+			// transposed
+			if err = a.T(); err != nil {
+				t.Fatal(err)
+			}
+			if c, err = op.eng(e, a); err != nil {
+				t.Fatal(err)
+			}
+			stdT := stdTensor(want, 2, 3)
+			if err = stdT.T(); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(allWithinRange(rowMajorData(t, stdT), readback(t, e, c).Data().([]float32), closef32), "transposed")
+			if err = a.T(); err != nil {
+				t.Fatal(err)
+			}
+
+			if c, err = op.eng(e, a, tensor.UseUnsafe()); err != nil {
+				t.Fatal(err)
+			}
+			assert.True(c == a)
+			assert.True(allWithinRange(want, readback(t, e, a).Data().([]float32), closef32), "unsafe")
+		})
+	}
+}
+
+func TestEngine_UnaryOpErrors(t *testing.T) {
+	e := newTestEngine(t)
+	i := engineCopy(t, e, tensor.New(tensor.WithBacking([]int32{1, 2, 3})))
+	live := liveAllocs(e)
+	_, err := e.Exp(i)
+	assert.EqualError(t, err, "Exp(): Unsupported Dtype int32: there is no kernel exp_i32")
+	assert.Equal(t, live, liveAllocs(e), "nothing should have been allocated")
+
+	// a is not in the memory of the Engine, so the op fails once its result is allocated
+	_, err = e.Exp(tensor.New(tensor.WithShape(3), tensor.Of(tensor.Float32)))
+	assert.Error(t, err, "Go memory")
+	assert.Equal(t, live, liveAllocs(e), "the result should have been released")
+}
+
+func TestHostSign(t *testing.T) {
+	assert.Equal(t, float32(1), hostSign(3))
+	assert.Equal(t, float32(-1), hostSign(-0.5))
+	assert.Equal(t, float32(0), hostSign(math32.NaN()))
+	assert.True(t, math32.Signbit(hostSign(math32.Copysign(0, -1))))
+}